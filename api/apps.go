@@ -2,9 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/forecast"
 	"github.com/microscaling/microscaling/metric"
+	"github.com/microscaling/microscaling/schedule"
 	"github.com/microscaling/microscaling/target"
 	"github.com/microscaling/microscaling/utils"
 )
@@ -18,15 +22,37 @@ type AppsMessage struct {
 
 // AppDescription is the json describing an individual app
 type AppDescription struct {
-	Name              string          `json:"name"`
-	Priority          int             `json:"priority"` // 1 is the highest, 0 means it's not scalable
-	MinContainers     int             `json:"minContainers"`
-	MaxContainers     int             `json:"maxContainers"`
-	TargetQueueLength int             `json:"targetValue"`
-	RuleType          string          `json:"ruleType"`
-	AppType           string          `json:"appType"`
-	MetricType        string          `json:"metricType"`
-	Config            DockerAppConfig `json:"config"`
+	Name                string          `json:"name"`
+	Priority            int             `json:"priority"` // 1 is the highest, 0 means it's not scalable
+	MinContainers       int             `json:"minContainers"`
+	MaxContainers       int             `json:"maxContainers"`
+	MaxDelta            int             `json:"maxDelta"`            // defaults to the difference between max and min containers
+	ScaleUpCooldown     int             `json:"scaleUpCooldown"`     // seconds
+	ScaleDownCooldown   int             `json:"scaleDownCooldown"`   // seconds
+	StabilizationWindow int             `json:"stabilizationWindow"` // seconds
+	TargetQueueLength   int             `json:"targetValue"`
+	RuleType            string          `json:"ruleType"`
+	AppType             string          `json:"appType"`
+	MetricType          string          `json:"metricType"`
+	Config              DockerAppConfig `json:"config"`
+
+	// Times when we override the min and max containers and priority, or set the containers for the Schedule rule type
+	Schedule []schedule.WindowConfig `json:"schedule"`
+
+	// Metrics for the Composite metric type, combined with the aggregation (sum or max)
+	Metrics     []MetricDescription `json:"metrics"`
+	Aggregation string              `json:"aggregation"`
+
+	// Scale on where the metric is heading rather than its current value
+	Forecast *forecast.Config `json:"forecast"`
+}
+
+// MetricDescription is the json describing one of the metrics in a composite. Its value is multiplied by the weight, which defaults to 1.
+// A weight of 0 leaves the metric out.
+type MetricDescription struct {
+	MetricType string          `json:"metricType"`
+	Weight     *float64        `json:"weight,omitempty"`
+	Config     DockerAppConfig `json:"config"`
 }
 
 // DockerAppConfig is the json describing parameters that need to be passed into Docker when starting this app
@@ -40,6 +66,39 @@ type DockerAppConfig struct {
 	TopicName       string `json:"topicName"`
 	ChannelName     string `json:"channelName"`
 	QueueURL        string `json:"queueURL"`
+	PrometheusURL   string `json:"prometheusURL"`
+	Query           string `json:"query"`
+	RabbitMQURL     string `json:"rabbitMQURL"`
+	VHost           string `json:"vhost"`
+	IncludeUnacked  bool   `json:"includeUnacked"`
+	BurrowURL       string `json:"burrowURL"`
+	Cluster         string `json:"cluster"`
+	ConsumerGroup   string `json:"consumerGroup"`
+	LagMode         string `json:"lagMode"` // sum or max
+	RedisAddress    string `json:"redisAddress"`
+	RedisDB         int    `json:"redisDB"`
+	RedisCommand    string `json:"redisCommand"` // LLEN, ZCARD or XPENDING
+	Key             string `json:"key"`
+	CPU             string `json:"cpu"`    // CPU requested per container, e.g. "500m"
+	Memory          string `json:"memory"` // Memory requested per container, e.g. "256Mi"
+	Kind            string `json:"kind"`   // Kubernetes workload to scale, defaults to Deployment
+
+	// Controller settings for the Queue rule type: kp, ki, kd, ku, tu and velSamples
+	target.PIDConfig
+	AutoTune  bool `json:"autoTune"`  // work out the controller settings by watching the queue
+	RelayStep int  `json:"relayStep"` // containers to scale up and down by while auto-tuning
+
+	// Value per container we aim for with the Utilization rule type, e.g. 100 messages or 70 (% CPU)
+	PerContainer float64 `json:"targetPerContainer"`
+
+	// Latency budget for the Latency rule type, in the same units as the metric (usually milliseconds)
+	LatencyBudget int `json:"latencyBudget"`
+
+	// Metric ranges and the number of containers to add or remove in each, for the Step rule type
+	Steps []target.Step `json:"steps"`
+
+	// Seconds within which every message on the queue should be processed, for the DrainTime rule type
+	DrainDeadline int `json:"drainDeadline"`
 }
 
 // AppsFromData converts apps data from json into tasks.
@@ -49,66 +108,172 @@ func AppsFromData(b []byte) (tasks []*demand.Task, maxContainers int, err error)
 	err = json.Unmarshal(b, &appsMessage)
 	if err != nil {
 		log.Debugf("Error unmarshalling from %s", string(b[:]))
+		log.Debugf("Apps message: %v", appsMessage)
+		return nil, appsMessage.MaxContainers, err
 	}
 
+	return AppsFromMessage(appsMessage)
+}
+
+// AppsFromMessage converts an apps message that has already been decoded into tasks.
+func AppsFromMessage(appsMessage AppsMessage) (tasks []*demand.Task, maxContainers int, err error) {
 	maxContainers = appsMessage.MaxContainers
 
 	for _, a := range appsMessage.Apps {
-		task := demand.Task{
-			Name:          a.Name,
-			Image:         a.Config.Image,
-			Command:       a.Config.Command,
-			Priority:      a.Priority,
-			MinContainers: a.MinContainers,
-			MaxContainers: a.MaxContainers,
-			MaxDelta:      (a.MaxContainers - a.MinContainers),
-			IsScalable:    true,
-
-			// TODO!! Settings that need to be made configurable via the API.
-			// Default PublishAllPorts to true.
-			PublishAllPorts: true,
-			// Set Network mode to host only. This won't work for load balancer metrics.
-			NetworkMode: "host",
+		task, err := TaskFromApp(a)
+		if err != nil {
+			return tasks, maxContainers, err
 		}
 
-		switch a.RuleType {
-		case "Queue":
-			task.Target = target.NewQueueLengthTarget(a.Config.QueueLength)
-		case "SimpleQueue":
-			task.Target = target.NewSimpleQueueLengthTarget(a.Config.QueueLength)
-		default:
-			task.Target = target.NewRemainderTarget(a.MaxContainers)
-			task.Metric = metric.NewNullMetric()
-		}
+		tasks = append(tasks, task)
+	}
+
+	return
+}
+
+// TaskFromApp creates a task, including its target and metric, from an app description.
+func TaskFromApp(a AppDescription) (task *demand.Task, err error) {
+	maxDelta := a.MaxDelta
+	if maxDelta == 0 {
+		maxDelta = a.MaxContainers - a.MinContainers
+	}
+
+	task = &demand.Task{
+		Name:          a.Name,
+		Kind:          a.Config.Kind,
+		Image:         a.Config.Image,
+		Command:       a.Config.Command,
+		Priority:      a.Priority,
+		MinContainers: a.MinContainers,
+		MaxContainers: a.MaxContainers,
+		MaxDelta:      maxDelta,
+		IsScalable:    true,
 
-		if a.RuleType == "Queue" || a.RuleType == "SimpleQueue" {
-			switch a.MetricType {
-			case "AzureQueue":
-				task.Metric = metric.NewAzureQueueMetric(a.Config.QueueName)
-			case "NSQ":
-				task.Metric = metric.NewNSQMetric(a.Config.TopicName, a.Config.ChannelName)
-			case "SQS":
-				metric, err := metric.NewSQSMetric(a.Config.QueueURL)
-				if err != nil {
-					log.Errorf("Failed to create SQS metric: %v", err)
-					return tasks, maxContainers, err
-				}
-
-				task.Metric = metric
-
-			default:
-				log.Errorf("Unexpected queue metricType %s", a.MetricType)
-			}
+		ScaleUpCooldown:     time.Duration(a.ScaleUpCooldown) * time.Second,
+		ScaleDownCooldown:   time.Duration(a.ScaleDownCooldown) * time.Second,
+		StabilizationWindow: time.Duration(a.StabilizationWindow) * time.Second,
+
+		// TODO!! Settings that need to be made configurable via the API.
+		// Default PublishAllPorts to true.
+		PublishAllPorts: true,
+		// Set Network mode to host only. This won't work for load balancer metrics.
+		NetworkMode: "host",
+	}
+
+	if len(a.Schedule) > 0 {
+		task.Schedule, err = schedule.New(a.Schedule)
+		if err != nil {
+			return task, fmt.Errorf("Bad schedule for %s: %v", a.Name, err)
 		}
+	}
 
-		tasks = append(tasks, &task)
+	task.Resources.CPU, err = utils.ParseCPU(a.Config.CPU)
+	if err != nil {
+		return task, fmt.Errorf("Bad cpu %s for %s: %v", a.Config.CPU, a.Name, err)
 	}
 
+	task.Resources.Memory, err = utils.ParseMemory(a.Config.Memory)
 	if err != nil {
-		log.Debugf("Apps message: %v", appsMessage)
+		return task, fmt.Errorf("Bad memory %s for %s: %v", a.Config.Memory, a.Name, err)
 	}
 
-	return
+	switch a.RuleType {
+	case "Queue":
+		q := target.NewTunedQueueLengthTarget(a.Config.QueueLength, a.Config.PIDConfig)
+		if a.Config.AutoTune {
+			q.AutoTune(a.Name, a.Config.RelayStep)
+		}
+		task.Target = q
+	case "SimpleQueue":
+		task.Target = target.NewSimpleQueueLengthTarget(a.Config.QueueLength)
+	case "Utilization":
+		task.Target = target.NewUtilizationTarget(a.Config.PerContainer)
+	case "Latency":
+		task.Target = target.NewLatencyTarget(a.Config.LatencyBudget)
+	case "Step":
+		task.Target = target.NewStepTarget(a.Config.Steps)
+	case "DrainTime":
+		task.Target = target.NewDrainTimeTarget(time.Duration(a.Config.DrainDeadline) * time.Second)
+	case "Schedule":
+		task.Target = target.NewScheduleTarget(task.Schedule)
+		task.Metric = metric.NewNullMetric()
+	default:
+		task.Target = target.NewRemainderTarget(a.MaxContainers)
+		task.Metric = metric.NewNullMetric()
+	}
+
+	if a.RuleType == "Queue" || a.RuleType == "SimpleQueue" || a.RuleType == "Utilization" || a.RuleType == "Latency" || a.RuleType == "Step" ||
+		a.RuleType == "DrainTime" {
+		task.Metric, err = metricFromApp(a)
+		if err != nil {
+			return task, err
+		}
+	}
+
+	if a.Forecast != nil && task.Metric != nil {
+		f, err := forecast.New(*a.Forecast)
+		if err != nil {
+			return task, fmt.Errorf("Bad forecast for %s: %v", a.Name, err)
+		}
+		task.Metric = metric.NewPredictiveMetric(task.Metric, f)
+	}
+
+	return task, nil
+}
+
+// metricFromApp creates the metric for an app, including any child metrics for a composite
+func metricFromApp(a AppDescription) (m metric.Metric, err error) {
+	if a.MetricType != "Composite" {
+		return newMetric(a.MetricType, a.Config)
+	}
+
+	var children []metric.Metric
+	var weights []float64
+	for _, md := range a.Metrics {
+		child, err := newMetric(md.MetricType, md.Config)
+		if err != nil {
+			return nil, fmt.Errorf("Bad composite metric for %s: %v", a.Name, err)
+		}
+
+		weight := 1.0
+		if md.Weight != nil {
+			weight = *md.Weight
+		}
+
+		children = append(children, child)
+		weights = append(weights, weight)
+	}
+
+	return metric.NewCompositeMetric(children, weights, a.Aggregation), nil
+}
+
+// newMetric creates a metric of the given type
+func newMetric(metricType string, c DockerAppConfig) (m metric.Metric, err error) {
+	switch metricType {
+	case "AzureQueue":
+		m = metric.NewAzureQueueMetric(c.QueueName)
+	case "NSQ":
+		m = metric.NewNSQMetric(c.TopicName, c.ChannelName)
+	case "Prometheus":
+		m = metric.NewPrometheusMetric(c.PrometheusURL, c.Query)
+	case "Kafka":
+		m = metric.NewKafkaLagMetric(c.BurrowURL, c.Cluster, c.ConsumerGroup, c.TopicName, c.LagMode)
+	case "Redis":
+		m = metric.NewRedisMetric(c.RedisAddress, c.RedisDB, c.RedisCommand, c.Key, c.ConsumerGroup)
+	case "RabbitMQ":
+		m = metric.NewRabbitMQMetric(c.RabbitMQURL, c.VHost, c.QueueName, c.IncludeUnacked)
+	case "SQS":
+		m, err = metric.NewSQSMetric(c.QueueURL)
+		if err != nil {
+			log.Errorf("Failed to create SQS metric: %v", err)
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("Unexpected metricType %s", metricType)
+	}
+
+	return m, nil
 }
 
 // GetApps retrives the app definitions from the server for a given userID
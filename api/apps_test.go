@@ -24,8 +24,15 @@ func TestGetAppsDecode(t *testing.T) {
 		t.Fatalf("Didn't decode command")
 	}
 
+	bb = []byte(`{"targetQueueLength": 10, "kp": 0.5, "velSamples": 3}`)
+	d = DockerAppConfig{}
+	_ = json.Unmarshal(bb, &d)
+	if d.KP == nil || *d.KP != 0.5 || d.VelSamples == nil || *d.VelSamples != 3 || d.KI != nil {
+		t.Fatalf("Didn't decode PID config")
+	}
+
 	// var response string = `"apps": [{"name":"priority1","appType":"Docker","config":{"image":"force12io/priority-1:latest","command":"/run.sh"}},{"name":"priority2","type":"Docker","config":{"image":"force12io/priority-2:latest","command":"/run.sh"}}]`
-	var response = `{"apps" : [{"name":"priority1", "config":{"image":"microscaling/priority-1:latest","command":"/run.sh"}},{"name":"priority2","appType":"Docker","config":{"image":"microscaling/priority-2:latest","command":"/run.sh"}}]}`
+	var response = `{"apps" : [{"name":"priority1", "config":{"image":"microscaling/priority-1:latest","command":"/run.sh"}},{"name":"priority2","appType":"Docker","config":{"image":"microscaling/priority-2:latest","command":"/run.sh","kind":"StatefulSet"}}]}`
 	var b = []byte(response)
 
 	var a AppsMessage
@@ -52,6 +59,9 @@ func TestGetAppsDecode(t *testing.T) {
 	if p2.Image != "microscaling/priority-2:latest" {
 		t.Fatalf("Bad image %s", p2.Image)
 	}
+	if p1.Kind != "" || p2.Kind != "StatefulSet" {
+		t.Fatalf("Bad kinds %s and %s", p1.Kind, p2.Kind)
+	}
 }
 
 func TestGetApps(t *testing.T) {
@@ -90,6 +100,28 @@ func TestGetApps(t *testing.T) {
 			              "command": "do this",
 			              "queueURL": "https://sqs.us-east-1.amazonaws.com/12345/test"
 			          }
+			      },
+			      {
+			          "name": "priority3",
+			          "appType": "Docker",
+			          "ruleType": "Queue",
+			          "metricType": "Prometheus",
+			          "config": {
+			              "image": "thirdimage",
+			              "prometheusURL": "http://prometheus:9090",
+			              "query": "sum(kafka_consumergroup_lag)"
+			          }
+			      },
+			      {
+			          "name": "priority4",
+			          "appType": "Docker",
+			          "ruleType": "Queue",
+			          "metricType": "RabbitMQ",
+			          "config": {
+			              "image": "fourthimage",
+			              "queueName": "jobs",
+			              "includeUnacked": true
+			          }
 			      }
 			]}`,
 			success: true,
@@ -101,14 +133,24 @@ func TestGetApps(t *testing.T) {
 					Image:   "anotherimage",
 					Command: "do this",
 				},
+				"priority3": demand.Task{
+					Image: "thirdimage",
+				},
+				"priority4": demand.Task{
+					Image: "fourthimage",
+				},
 			},
 			metricTypes: map[string]string{
 				"priority1": "*metric.NSQMetric",
 				"priority2": "*metric.SQSMetric",
+				"priority3": "*metric.PrometheusMetric",
+				"priority4": "*metric.RabbitMQMetric",
 			},
 			targetTypes: map[string]string{
 				"priority1": "*target.QueueLengthTarget",
 				"priority2": "*target.SimpleQueueLengthTarget",
+				"priority3": "*target.QueueLengthTarget",
+				"priority4": "*target.QueueLengthTarget",
 			},
 		},
 		{
@@ -215,3 +257,20 @@ func CheckTargetTypes(t *testing.T, targetTypes map[string]string, returnedTasks
 		}
 	}
 }
+
+func TestTaskFromAppUnknownMetric(t *testing.T) {
+	_, err := TaskFromApp(AppDescription{Name: "app", RuleType: "Queue", MetricType: "Carrier pigeon"})
+	if err == nil {
+		t.Errorf("Expected an error for an unknown metric type")
+	}
+
+	_, err = TaskFromApp(AppDescription{
+		Name:       "app",
+		RuleType:   "Queue",
+		MetricType: "Composite",
+		Metrics:    []MetricDescription{{MetricType: "NSQ"}, {MetricType: "Carrier pigeon"}},
+	})
+	if err == nil {
+		t.Errorf("Expected an error for an unknown metric type in a composite")
+	}
+}
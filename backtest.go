@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/forecast"
+	"github.com/microscaling/microscaling/metric"
+	"github.com/microscaling/microscaling/target"
+)
+
+const constBacktestSampleInterval = 500 // milliseconds - the local engine reads metrics this often
+const constBacktestHorizon = "30s"      // how far ahead to forecast if the task doesn't have forecast config
+
+// runBacktest replays the recorded metric in the backtest file through a task's target, and reports how
+// reactive and predictive scaling would have behaved
+func runBacktest(st settings, w io.Writer) error {
+	c, err := getConfig(st)
+	if err != nil {
+		return err
+	}
+
+	// Each run needs a fresh copy of the target, so we load the config each time
+	loadTask := func() (*demand.Task, error) {
+		tasks, err := loadTasks(c, st)
+		if err != nil {
+			return nil, err
+		}
+
+		if st.backtestTask == "" && len(tasks.Tasks) > 0 {
+			return tasks.Tasks[0], nil
+		}
+		return tasks.GetTask(st.backtestTask)
+	}
+
+	task, err := loadTask()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(st.backtestFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	samples, err := forecast.ReadSamples(f, constBacktestSampleInterval*time.Millisecond)
+	if err != nil {
+		return err
+	}
+
+	b := forecast.Backtest{
+		Forecast:      forecast.Config{Horizon: constBacktestHorizon},
+		StartupDelay:  st.backtestStartup,
+		MinContainers: task.MinContainers,
+		MaxContainers: task.MaxContainers,
+
+		ScaleUpCooldown:     task.ScaleUpCooldown,
+		ScaleDownCooldown:   task.ScaleDownCooldown,
+		StabilizationWindow: task.StabilizationWindow,
+		NewTarget: func() target.Target {
+			t, err := loadTask()
+			if err != nil {
+				log.Errorf("Failed to load task for backtest: %v", err)
+				return task.Target
+			}
+			return t.Target
+		},
+	}
+
+	if p, ok := task.Metric.(*metric.PredictiveMetric); ok {
+		b.Forecast = p.Forecaster().Config()
+	}
+
+	result, err := b.Run(samples)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Backtest for %s: %d samples, forecasting %s ahead, containers take %v to start\n", task.Name, result.Samples, b.Forecast.Horizon, b.StartupDelay)
+	fmt.Fprintf(w, "Mean forecast error: %.2f\n", result.ForecastError)
+	fmt.Fprintf(w, "%-12s %10s %16s %18s %18s %14s\n", "", "scale ops", "mean containers", "under-provisioned", "over-provisioned", "samples short")
+	for _, o := range []struct {
+		name    string
+		outcome forecast.Outcome
+	}{
+		{"reactive", result.Reactive},
+		{"predictive", result.Predictive},
+	} {
+		fmt.Fprintf(w, "%-12s %10d %16.2f %18d %18d %14d\n", o.name, o.outcome.ScaleOps, o.outcome.MeanContainers, o.outcome.UnderProvisioned, o.outcome.OverProvisioned, o.outcome.SamplesUnderScale)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunBacktest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microscaling")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "microscaling.yml")
+	config := `
+maxContainers: 20
+apps:
+- name: worker
+  minContainers: 1
+  maxContainers: 20
+  ruleType: Utilization
+  metricType: NSQ
+  config:
+    targetPerContainer: 100
+    topicName: work
+    channelName: work
+  forecast:
+    horizon: 10s
+`
+	err = ioutil.WriteFile(configFile, []byte(config), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	var samples bytes.Buffer
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&samples, "%d\n", i*5)
+	}
+
+	samplesFile := filepath.Join(dir, "samples.txt")
+	err = ioutil.WriteFile(samplesFile, samples.Bytes(), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write samples file: %v", err)
+	}
+
+	st := settings{
+		config:          "FILE",
+		configFile:      configFile,
+		backtestFile:    samplesFile,
+		backtestStartup: 5 * time.Second,
+	}
+
+	var out bytes.Buffer
+	err = runBacktest(st, &out)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for _, expected := range []string{"Backtest for worker: 200 samples, forecasting 10s ahead", "reactive", "predictive"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to contain %q but was %s", expected, out.String())
+		}
+	}
+
+	st.backtestTask = "nonexistent"
+	if err = runBacktest(st, &out); err == nil {
+		t.Fatalf("Expected an error for a task that doesn't exist")
+	}
+}
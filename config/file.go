@@ -0,0 +1,334 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/microscaling/microscaling/api"
+	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/forecast"
+	"github.com/microscaling/microscaling/metric"
+	"github.com/microscaling/microscaling/schedule"
+	"github.com/microscaling/microscaling/scheduler/kubernetes"
+	"github.com/microscaling/microscaling/target"
+	"github.com/microscaling/microscaling/utils"
+)
+
+// FileConfig is used when we read task config from a local YAML or JSON file. The file has the
+// same structure as the apps message we get from the server.
+type FileConfig struct {
+	FilePath string
+	modTime  time.Time
+}
+
+// compile-time assert that we implement the right interfaces
+var _ Config = (*FileConfig)(nil)
+var _ Changer = (*FileConfig)(nil)
+
+// NewFileConfig gets a new FileConfig
+func NewFileConfig(filePath string) *FileConfig {
+	return &FileConfig{
+		FilePath: filePath,
+	}
+}
+
+// GetApps reads task config from the file. JSON is a subset of YAML so we can parse either format.
+func (f *FileConfig) GetApps(userID string) (tasks []*demand.Task, maxContainers int, err error) {
+	var appsMessage api.AppsMessage
+
+	// Note the modification time before reading, so we'll see any changes made while we're reading
+	if info, err := os.Stat(f.FilePath); err == nil {
+		f.modTime = info.ModTime()
+	}
+
+	b, err := ioutil.ReadFile(f.FilePath)
+	if err != nil {
+		log.Errorf("Failed to read config file %s: %v", f.FilePath, err)
+		return nil, 0, err
+	}
+
+	err = yaml.Unmarshal(b, &appsMessage)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Failed to parse config file %s: %v", f.FilePath, err)
+	}
+
+	errs := validateApps(appsMessage)
+	if len(errs) > 0 {
+		return nil, 0, fmt.Errorf("Invalid config file %s: %s", f.FilePath, strings.Join(errs, "; "))
+	}
+
+	return api.AppsFromMessage(appsMessage)
+}
+
+// Changed returns true if the file has been modified since we last read it
+func (f *FileConfig) Changed() bool {
+	info, err := os.Stat(f.FilePath)
+	if err != nil {
+		return false
+	}
+
+	return !info.ModTime().Equal(f.modTime)
+}
+
+// validateApps checks the config for all tasks, so that we can report every problem at once
+// rather than making the user fix them one by one.
+func validateApps(appsMessage api.AppsMessage) (errs []string) {
+	if appsMessage.MaxContainers <= 0 {
+		errs = append(errs, fmt.Sprintf("maxContainers must be greater than 0 but was %d", appsMessage.MaxContainers))
+	}
+
+	if len(appsMessage.Apps) == 0 {
+		errs = append(errs, "no apps configured")
+	}
+
+	names := make(map[string]bool, len(appsMessage.Apps))
+
+	for i, a := range appsMessage.Apps {
+		if a.Name == "" {
+			errs = append(errs, fmt.Sprintf("app %d: name is required", i))
+		} else if names[a.Name] {
+			errs = append(errs, fmt.Sprintf("app %d: name %s is used more than once", i, a.Name))
+		}
+		names[a.Name] = true
+
+		for _, e := range validateApp(a) {
+			errs = append(errs, fmt.Sprintf("task %s: %s", a.Name, e))
+		}
+	}
+
+	return errs
+}
+
+func validateApp(a api.AppDescription) (errs []string) {
+	if a.Priority < 0 {
+		errs = append(errs, fmt.Sprintf("priority must not be negative but was %d", a.Priority))
+	}
+
+	if a.MinContainers < 0 {
+		errs = append(errs, fmt.Sprintf("minContainers must not be negative but was %d", a.MinContainers))
+	}
+
+	if a.MaxContainers < a.MinContainers {
+		errs = append(errs, fmt.Sprintf("maxContainers (%d) must not be less than minContainers (%d)", a.MaxContainers, a.MinContainers))
+	}
+
+	if a.MaxDelta < 0 {
+		errs = append(errs, fmt.Sprintf("maxDelta must not be negative but was %d", a.MaxDelta))
+	}
+
+	durations := []struct {
+		field string
+		value int
+	}{
+		{"scaleUpCooldown", a.ScaleUpCooldown},
+		{"scaleDownCooldown", a.ScaleDownCooldown},
+		{"stabilizationWindow", a.StabilizationWindow},
+	}
+
+	for _, d := range durations {
+		if d.value < 0 {
+			errs = append(errs, fmt.Sprintf("%s must not be negative but was %d", d.field, d.value))
+		}
+	}
+
+	if _, err := schedule.New(a.Schedule); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if a.Forecast != nil {
+		if _, err := forecast.New(*a.Forecast); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if _, err := utils.ParseCPU(a.Config.CPU); err != nil {
+		errs = append(errs, fmt.Sprintf("config.cpu %s is not a valid quantity", a.Config.CPU))
+	}
+
+	if _, err := utils.ParseMemory(a.Config.Memory); err != nil {
+		errs = append(errs, fmt.Sprintf("config.memory %s is not a valid quantity", a.Config.Memory))
+	}
+
+	if a.Config.Kind != "" {
+		kinds := kubernetes.Kinds()
+		supported := false
+		for _, k := range kinds {
+			supported = supported || k == a.Config.Kind
+		}
+
+		if !supported {
+			errs = append(errs, fmt.Sprintf("config.kind %s is not supported, use one of %s", a.Config.Kind, strings.Join(kinds, ", ")))
+		}
+	}
+
+	errs = append(errs, validatePID(a.Config.PIDConfig)...)
+
+	if a.Config.RelayStep < 0 {
+		errs = append(errs, fmt.Sprintf("config.relayStep must not be negative but was %d", a.Config.RelayStep))
+	}
+
+	switch a.RuleType {
+	case "Queue", "SimpleQueue":
+		if a.Config.QueueLength <= 0 {
+			errs = append(errs, fmt.Sprintf("config.targetQueueLength must be greater than 0 for ruleType %s", a.RuleType))
+		}
+
+		errs = append(errs, validateMetric(a)...)
+	case "Utilization":
+		if a.Config.PerContainer <= 0 {
+			errs = append(errs, fmt.Sprintf("config.targetPerContainer must be greater than 0 for ruleType %s", a.RuleType))
+		}
+
+		errs = append(errs, validateMetric(a)...)
+	case "Latency":
+		if a.Config.LatencyBudget <= 0 {
+			errs = append(errs, fmt.Sprintf("config.latencyBudget must be greater than 0 for ruleType %s", a.RuleType))
+		}
+
+		errs = append(errs, validateMetric(a)...)
+	case "DrainTime":
+		if a.Config.DrainDeadline <= 0 {
+			errs = append(errs, fmt.Sprintf("config.drainDeadline must be greater than 0 for ruleType %s", a.RuleType))
+		}
+
+		errs = append(errs, validateMetric(a)...)
+	case "Step":
+		if err := target.ValidateSteps(a.Config.Steps); err != nil {
+			errs = append(errs, fmt.Sprintf("config.steps: %v", err))
+		}
+
+		errs = append(errs, validateMetric(a)...)
+	case "Schedule":
+		if len(a.Schedule) == 0 {
+			errs = append(errs, fmt.Sprintf("schedule is required for ruleType %s", a.RuleType))
+		}
+	case "", "Remainder":
+	default:
+		errs = append(errs, fmt.Sprintf("ruleType %s is not supported", a.RuleType))
+	}
+
+	return errs
+}
+
+func validatePID(pid target.PIDConfig) (errs []string) {
+	gains := []struct {
+		field string
+		value *float64
+	}{
+		{"config.kp", pid.KP},
+		{"config.ki", pid.KI},
+		{"config.kd", pid.KD},
+		{"config.ku", pid.KU},
+	}
+
+	for _, g := range gains {
+		if g.value != nil && *g.value < 0 {
+			errs = append(errs, fmt.Sprintf("%s must not be negative but was %f", g.field, *g.value))
+		}
+	}
+
+	if pid.TU != nil && *pid.TU <= 0 {
+		errs = append(errs, fmt.Sprintf("config.tu must be greater than 0 but was %f", *pid.TU))
+	}
+
+	if pid.VelSamples != nil && *pid.VelSamples <= 0 {
+		errs = append(errs, fmt.Sprintf("config.velSamples must be greater than 0 but was %d", *pid.VelSamples))
+	}
+
+	return errs
+}
+
+// requiredField is a config field that must be set for a particular metric type
+type requiredField struct {
+	field string
+	value string
+}
+
+func validateMetric(a api.AppDescription) (errs []string) {
+	switch a.MetricType {
+	case "Composite":
+		if len(a.Metrics) == 0 {
+			errs = append(errs, "metrics are required for metricType Composite")
+		}
+
+		switch strings.ToLower(a.Aggregation) {
+		case "", metric.CompositeSum, metric.CompositeMax:
+		default:
+			errs = append(errs, fmt.Sprintf("aggregation %s is not supported", a.Aggregation))
+		}
+
+		for i, m := range a.Metrics {
+			if m.MetricType == "Composite" {
+				errs = append(errs, fmt.Sprintf("metrics %d: composite metrics can't be nested", i))
+				continue
+			}
+
+			if m.Weight != nil && *m.Weight < 0 {
+				errs = append(errs, fmt.Sprintf("metrics %d: weight must not be negative but was %f", i, *m.Weight))
+			}
+
+			for _, e := range validateMetricConfig(m.MetricType, m.Config) {
+				errs = append(errs, fmt.Sprintf("metrics %d: %s", i, e))
+			}
+		}
+	case "":
+		errs = append(errs, fmt.Sprintf("metricType is required for ruleType %s", a.RuleType))
+	default:
+		errs = append(errs, validateMetricConfig(a.MetricType, a.Config)...)
+	}
+
+	return errs
+}
+
+// validateMetricConfig checks the config for a single metric
+func validateMetricConfig(metricType string, c api.DockerAppConfig) (errs []string) {
+	var required []requiredField
+
+	switch metricType {
+	case "AzureQueue":
+		required = []requiredField{{"config.queueName", c.QueueName}}
+	case "NSQ":
+		required = []requiredField{{"config.topicName", c.TopicName}, {"config.channelName", c.ChannelName}}
+	case "Prometheus":
+		required = []requiredField{{"config.query", c.Query}}
+	case "Kafka":
+		required = []requiredField{{"config.cluster", c.Cluster}, {"config.consumerGroup", c.ConsumerGroup}, {"config.topicName", c.TopicName}}
+
+		switch c.LagMode {
+		case "", metric.KafkaLagSum, metric.KafkaLagMax:
+		default:
+			errs = append(errs, fmt.Sprintf("config.lagMode %s is not supported", c.LagMode))
+		}
+	case "Redis":
+		required = []requiredField{{"config.key", c.Key}}
+
+		switch strings.ToUpper(c.RedisCommand) {
+		case "", metric.RedisLLEN, metric.RedisZCARD:
+		case metric.RedisXPENDING:
+			required = append(required, requiredField{"config.consumerGroup", c.ConsumerGroup})
+		default:
+			errs = append(errs, fmt.Sprintf("config.redisCommand %s is not supported", c.RedisCommand))
+		}
+	case "RabbitMQ":
+		required = []requiredField{{"config.queueName", c.QueueName}}
+	case "SQS":
+		required = []requiredField{{"config.queueURL", c.QueueURL}}
+	case "":
+		errs = append(errs, "metricType is required")
+	default:
+		errs = append(errs, fmt.Sprintf("metricType %s is not supported", metricType))
+	}
+
+	for _, r := range required {
+		if r.value == "" {
+			errs = append(errs, fmt.Sprintf("%s is required for metricType %s", r.field, metricType))
+		}
+	}
+
+	return errs
+}
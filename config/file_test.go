@@ -0,0 +1,450 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		fileName      string
+		contents      string
+		success       bool
+		taskNames     []string
+		targetTypes   []string
+		metricTypes   []string
+		maxContainers int
+		errors        []string
+	}{
+		{
+			name:     "yaml",
+			fileName: "microscaling.yml",
+			contents: `
+maxContainers: 10
+apps:
+- name: consumer
+  priority: 1
+  minContainers: 1
+  maxContainers: 8
+  maxDelta: 2
+  ruleType: Queue
+  metricType: NSQ
+  config:
+    image: microscaling/queue-demo:latest
+    command: /run.sh
+    targetQueueLength: 50
+    topicName: demo
+    channelName: demo
+- name: worker
+  priority: 1
+  maxContainers: 8
+  ruleType: Utilization
+  metricType: NSQ
+  config:
+    targetPerContainer: 100
+    topicName: work
+    channelName: work
+- name: both
+  priority: 1
+  maxContainers: 8
+  ruleType: Queue
+  metricType: Composite
+  aggregation: max
+  config:
+    targetQueueLength: 50
+  metrics:
+  - metricType: NSQ
+    config:
+      topicName: demo
+      channelName: demo
+  - metricType: Prometheus
+    weight: 0.5
+    config:
+      query: sum(queue_length)
+- name: batch
+  priority: 2
+  maxContainers: 4
+  ruleType: Schedule
+  schedule:
+  - cron: 0 1 * * *
+    duration: 4h
+    timezone: Europe/London
+    containers: 4
+- name: remainder
+  priority: 2
+  maxContainers: 10
+  config:
+    image: microscaling/priority-2:latest
+  schedule:
+  - cron: 0 9 * * 1-5
+    duration: 8h
+    minContainers: 2
+`,
+			success:       true,
+			taskNames:     []string{"consumer", "worker", "both", "batch", "remainder"},
+			targetTypes:   []string{"*target.QueueLengthTarget", "*target.UtilizationTarget", "*target.QueueLengthTarget", "*target.ScheduleTarget", "*target.RemainderTarget"},
+			metricTypes:   []string{"*metric.NSQMetric", "*metric.NSQMetric", "*metric.CompositeMetric", "*metric.NullMetric", "*metric.NullMetric"},
+			maxContainers: 10,
+		},
+		{
+			name:     "json",
+			fileName: "microscaling.json",
+			contents: `{
+				"maxContainers": 5,
+				"apps": [
+					{
+						"name": "consumer",
+						"priority": 1,
+						"maxContainers": 5,
+						"ruleType": "SimpleQueue",
+						"metricType": "NSQ",
+						"config": {
+							"targetQueueLength": 10,
+							"topicName": "demo",
+							"channelName": "demo"
+						}
+					},
+					{
+						"name": "stepped",
+						"priority": 2,
+						"maxContainers": 5,
+						"ruleType": "Step",
+						"metricType": "NSQ",
+						"config": {
+							"topicName": "demo",
+							"channelName": "demo",
+							"steps": [
+								{"lower": 0, "upper": 100, "delta": -1},
+								{"lower": 100, "upper": 1000, "delta": 2},
+								{"lower": 1000, "delta": 5}
+							]
+						}
+					},
+					{
+						"name": "deadline",
+						"priority": 2,
+						"maxContainers": 5,
+						"ruleType": "DrainTime",
+						"metricType": "NSQ",
+						"config": {
+							"topicName": "orders",
+							"channelName": "orders",
+							"drainDeadline": 30
+						}
+					}
+				]
+			}`,
+			success:       true,
+			taskNames:     []string{"consumer", "stepped", "deadline"},
+			targetTypes:   []string{"*target.SimpleQueueLengthTarget", "*target.StepTarget", "*target.DrainTimeTarget"},
+			metricTypes:   []string{"*metric.NSQMetric", "*metric.NSQMetric", "*metric.NSQMetric"},
+			maxContainers: 5,
+		},
+		{
+			name:     "invalid values",
+			fileName: "microscaling.yml",
+			contents: `
+maxContainers: 0
+apps:
+- name: consumer
+  priority: -1
+  minContainers: 3
+  maxContainers: 2
+  scaleUpCooldown: -5
+  ruleType: Queue
+  metricType: NSQ
+  config:
+    topicName: demo
+- name: consumer
+  ruleType: Magic
+- ruleType: SimpleQueue
+  metricType: Carrier pigeon
+  config:
+    targetQueueLength: 5
+- name: web
+  ruleType: Queue
+  metricType: Prometheus
+  config:
+    targetQueueLength: 100
+    kind: DaemonSet
+- name: kafka
+  ruleType: Queue
+  metricType: Kafka
+  config:
+    targetQueueLength: 100
+    cluster: local
+    topicName: jobs
+    lagMode: min
+- name: redis
+  ruleType: Queue
+  metricType: Redis
+  config:
+    targetQueueLength: 100
+    key: events
+    redisCommand: XPENDING
+- name: utilization
+  ruleType: Utilization
+  metricType: NSQ
+  config:
+    topicName: demo
+    channelName: demo
+- name: frontend
+  ruleType: Latency
+  metricType: Prometheus
+  config:
+    query: histogram_quantile(0.95, rate(http_request_duration_seconds_bucket[1m])) * 1000
+- name: nightly
+  ruleType: Schedule
+- name: overnight
+  schedule:
+  - cron: 0 1 * *
+    duration: 4h
+- name: predicted
+  ruleType: SimpleQueue
+  metricType: NSQ
+  config:
+    targetQueueLength: 100
+    topicName: demo
+    channelName: demo
+  forecast:
+    horizon: soon
+- name: combined
+  ruleType: Queue
+  metricType: Composite
+  aggregation: avg
+  config:
+    targetQueueLength: 100
+  metrics:
+  - metricType: NSQ
+    weight: -1
+    config:
+      topicName: demo
+  - metricType: Composite
+- name: stepped
+  ruleType: Step
+  metricType: NSQ
+  config:
+    topicName: demo
+    channelName: demo
+    steps:
+    - lower: 0
+      upper: 100
+      delta: -1
+    - lower: 50
+      delta: 2
+- name: deadline
+  ruleType: DrainTime
+  metricType: NSQ
+  config:
+    topicName: demo
+    channelName: demo
+- name: tuned
+  ruleType: Queue
+  metricType: NSQ
+  config:
+    targetQueueLength: 100
+    topicName: demo
+    channelName: demo
+    kp: -1
+    velSamples: 0
+`,
+			success: false,
+			errors: []string{
+				"maxContainers must be greater than 0",
+				"task consumer: priority must not be negative",
+				"task consumer: scaleUpCooldown must not be negative",
+				"task consumer: maxContainers (2) must not be less than minContainers (3)",
+				"task consumer: config.targetQueueLength must be greater than 0",
+				"task consumer: config.channelName is required for metricType NSQ",
+				"name consumer is used more than once",
+				"task consumer: ruleType Magic is not supported",
+				"app 2: name is required",
+				"metricType Carrier pigeon is not supported",
+				"task web: config.query is required for metricType Prometheus",
+				"task web: config.kind DaemonSet is not supported",
+				"task kafka: config.consumerGroup is required for metricType Kafka",
+				"task kafka: config.lagMode min is not supported",
+				"task redis: config.consumerGroup is required for metricType Redis",
+				"task utilization: config.targetPerContainer must be greater than 0",
+				"task frontend: config.latencyBudget must be greater than 0",
+				"task nightly: schedule is required for ruleType Schedule",
+				"task overnight: Schedule window 0: Cron expression",
+				"task predicted: Bad forecast horizon",
+				"task combined: aggregation avg is not supported",
+				"task combined: metrics 0: weight must not be negative",
+				"task combined: metrics 0: config.channelName is required for metricType NSQ",
+				"task combined: metrics 1: composite metrics can't be nested",
+				"task stepped: config.steps: Steps 0-100:-1 and 50-:2 overlap",
+				"task deadline: config.drainDeadline must be greater than 0",
+				"task tuned: config.kp must not be negative",
+				"task tuned: config.velSamples must be greater than 0",
+			},
+		},
+		{
+			name:     "not yaml",
+			fileName: "microscaling.yml",
+			contents: "maxContainers: [",
+			success:  false,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "microscaling")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.fileName)
+			err := ioutil.WriteFile(path, []byte(tc.contents), 0644)
+			if err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+
+			c := NewFileConfig(path)
+			tasks, maxC, err := c.GetApps("test-user")
+
+			if !tc.success {
+				if err == nil {
+					t.Fatalf("Expected an error")
+				}
+
+				for _, e := range tc.errors {
+					if !strings.Contains(err.Error(), e) {
+						t.Errorf("Expected error to contain %q but was %v", e, err)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was %v", err)
+			}
+
+			if maxC != tc.maxContainers {
+				t.Fatalf("Expected max containers to be %d but was %d", tc.maxContainers, maxC)
+			}
+
+			if len(tasks) != len(tc.taskNames) {
+				t.Fatalf("Expected %d tasks but was %d", len(tc.taskNames), len(tasks))
+			}
+
+			for i, task := range tasks {
+				if task.Name != tc.taskNames[i] {
+					t.Errorf("Expected task %d name to be %s but was %s", i, tc.taskNames[i], task.Name)
+				}
+
+				typeName := reflect.TypeOf(task.Target).String()
+				if typeName != tc.targetTypes[i] {
+					t.Errorf("Expected task %d target to be %s but was %s", i, tc.targetTypes[i], typeName)
+				}
+
+				typeName = reflect.TypeOf(task.Metric).String()
+				if typeName != tc.metricTypes[i] {
+					t.Errorf("Expected task %d metric to be %s but was %s", i, tc.metricTypes[i], typeName)
+				}
+			}
+		})
+	}
+}
+
+func TestFileConfigMissingFile(t *testing.T) {
+	c := NewFileConfig("/this/file/does/not/exist.yml")
+	_, _, err := c.GetApps("test-user")
+	if err == nil {
+		t.Fatalf("Expected an error for a missing file")
+	}
+}
+
+func TestFileConfigMaxDelta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microscaling")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "microscaling.yml")
+	contents := `
+maxContainers: 10
+apps:
+- name: explicit
+  minContainers: 1
+  maxContainers: 8
+  maxDelta: 2
+  scaleDownCooldown: 30
+  stabilizationWindow: 120
+- name: default
+  minContainers: 1
+  maxContainers: 8
+`
+	err = ioutil.WriteFile(path, []byte(contents), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	tasks, _, err := NewFileConfig(path).GetApps("test-user")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if tasks[0].MaxDelta != 2 {
+		t.Errorf("Expected explicit max delta 2 but was %d", tasks[0].MaxDelta)
+	}
+
+	if tasks[1].MaxDelta != 7 {
+		t.Errorf("Expected default max delta 7 but was %d", tasks[1].MaxDelta)
+	}
+
+	if tasks[0].ScaleDownCooldown != 30*time.Second || tasks[0].StabilizationWindow != 2*time.Minute {
+		t.Errorf("Expected scale down cooldown 30s and stabilization window 2m but were %v and %v", tasks[0].ScaleDownCooldown, tasks[0].StabilizationWindow)
+	}
+
+	if tasks[1].ScaleUpCooldown != 0 || tasks[1].StabilizationWindow != 0 {
+		t.Errorf("Expected no cooldown or stabilization by default")
+	}
+}
+
+func TestFileConfigChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microscaling")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "microscaling.yml")
+	err = ioutil.WriteFile(path, []byte("maxContainers: 10\napps:\n- name: remainder\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	c := NewFileConfig(path)
+	if !c.Changed() {
+		t.Fatalf("Should be changed before the first read")
+	}
+
+	_, _, err = c.GetApps("test-user")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if c.Changed() {
+		t.Fatalf("Shouldn't be changed after reading")
+	}
+
+	later := time.Now().Add(time.Minute)
+	err = os.Chtimes(path, later, later)
+	if err != nil {
+		t.Fatalf("Failed to touch config file: %v", err)
+	}
+
+	if !c.Changed() {
+		t.Fatalf("Should be changed after the file is modified")
+	}
+}
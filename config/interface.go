@@ -10,4 +10,10 @@ type Config interface {
 	GetApps(userID string) (tasks []*demand.Task, maxContainers int, err error)
 }
 
+// Changer is implemented by config sources that can tell us cheaply whether their config has changed
+// since GetApps was last called, so that we know when to reload it
+type Changer interface {
+	Changed() bool
+}
+
 var log = logging.MustGetLogger("mssconfig")
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	microbadger "github.com/microscaling/microbadger/api"
 
 	"github.com/microscaling/microscaling/api"
 	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/metric"
+	"github.com/microscaling/microscaling/target"
 	"github.com/microscaling/microscaling/utils"
 )
 
@@ -117,6 +120,64 @@ func parseLabels(task *demand.Task, labels map[string]string) {
 	if err == nil {
 		task.MaxContainers = v
 	}
+
+	// Cooldowns and stabilization window are in seconds, and keep the values from the API config if they aren't set
+	if d, ok := parseSecondsLabel(labels, "com.microscaling.scale-up-cooldown"); ok {
+		task.ScaleUpCooldown = d
+	}
+
+	if d, ok := parseSecondsLabel(labels, "com.microscaling.scale-down-cooldown"); ok {
+		task.ScaleDownCooldown = d
+	}
+
+	if d, ok := parseSecondsLabel(labels, "com.microscaling.stabilization-window"); ok {
+		task.StabilizationWindow = d
+	}
+
+	// Step scaling replaces the target from the API config, e.g. com.microscaling.steps=0-100:-1,100-1000:2,1000-:5
+	// Steps need a metric to look up, so we can't use them for rule types that don't measure anything.
+	if ruleType, ok := labels["com.microscaling.rule-type"]; ok && ruleType == "Step" {
+		steps, err := target.ParseSteps(labels["com.microscaling.steps"])
+		if _, isNull := task.Metric.(*metric.NullMetric); task.Metric == nil || isNull {
+			log.Infof("Ignoring label com.microscaling.rule-type=Step as task %s doesn't have a metric", task.Name)
+		} else if err == nil {
+			task.Target = target.NewStepTarget(steps)
+		} else {
+			log.Infof("Ignoring bad value for label com.microscaling.steps: %v", err)
+		}
+	}
+
+	// Controller settings for tasks that use a queue length target
+	var pid target.PIDConfig
+	pid.KP = parseFloatLabel(labels, "com.microscaling.kp")
+	pid.KI = parseFloatLabel(labels, "com.microscaling.ki")
+	pid.KD = parseFloatLabel(labels, "com.microscaling.kd")
+	pid.KU = parseFloatLabel(labels, "com.microscaling.ku")
+	pid.TU = parseFloatLabel(labels, "com.microscaling.tu")
+
+	if velSamples, err := parseIntLabel(labels, "com.microscaling.vel-samples"); err == nil && velSamples > 0 {
+		pid.VelSamples = &velSamples
+	}
+
+	if t, ok := task.Target.(target.Tunable); ok && !pid.IsZero() {
+		t.Tune(pid)
+	}
+
+	if cpu, ok := labels["com.microscaling.cpu"]; ok {
+		if millicores, err := utils.ParseCPU(cpu); err == nil {
+			task.Resources.CPU = millicores
+		} else {
+			log.Infof("Ignoring bad value for label com.microscaling.cpu")
+		}
+	}
+
+	if memory, ok := labels["com.microscaling.memory"]; ok {
+		if bytes, err := utils.ParseMemory(memory); err == nil {
+			task.Resources.Memory = bytes
+		} else {
+			log.Infof("Ignoring bad value for label com.microscaling.memory")
+		}
+	}
 }
 
 func parseIntLabel(labels map[string]string, key string) (intVal int, err error) {
@@ -130,6 +191,36 @@ func parseIntLabel(labels map[string]string, key string) (intVal int, err error)
 	return
 }
 
+// parseSecondsLabel returns false if the label isn't set or isn't a number of seconds
+func parseSecondsLabel(labels map[string]string, key string) (time.Duration, bool) {
+	if _, ok := labels[key]; !ok {
+		return 0, false
+	}
+
+	v, err := parseIntLabel(labels, key)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+
+	return time.Duration(v) * time.Second, true
+}
+
+// parseFloatLabel returns nil if the label isn't set or isn't a number
+func parseFloatLabel(labels map[string]string, key string) *float64 {
+	val, ok := labels[key]
+	if !ok {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		log.Infof("Ignoring bad value for label %s", key)
+		return nil
+	}
+
+	return &f
+}
+
 func (kl *KubeLabelConfig) getImageFromKubeDeployment(appName string) (imageName string, err error) {
 	clientset, err := utils.NewKubeClientset(kl.KubeConfig, kl.KubeNamespace)
 	if err != nil {
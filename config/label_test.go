@@ -2,8 +2,11 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/metric"
+	"github.com/microscaling/microscaling/target"
 )
 
 func TestLabelConfig(t *testing.T) {
@@ -15,6 +18,11 @@ func TestLabelConfig(t *testing.T) {
 	labels["com.microscaling.max-delta"] = "2"
 	labels["com.microscaling.min-containers"] = "1"
 	labels["com.microscaling.MAX-containers"] = "20"
+	labels["com.microscaling.cpu"] = "250m"
+	labels["com.microscaling.memory"] = "64Mi"
+	labels["com.microscaling.scale-up-cooldown"] = "10"
+	labels["com.microscaling.scale-down-cooldown"] = "60"
+	labels["com.microscaling.stabilization-window"] = "300"
 
 	parseLabels(&task, labels)
 
@@ -38,4 +46,124 @@ func TestLabelConfig(t *testing.T) {
 		t.Errorf("Bad Min Containers")
 	}
 
+	if task.Resources.CPU != 250 {
+		t.Errorf("Bad CPU")
+	}
+
+	if task.Resources.Memory != 64*1024*1024 {
+		t.Errorf("Bad Memory")
+	}
+
+	if task.ScaleUpCooldown != 10*time.Second || task.ScaleDownCooldown != time.Minute {
+		t.Errorf("Bad cooldowns")
+	}
+
+	if task.StabilizationWindow != 5*time.Minute {
+		t.Errorf("Bad stabilization window")
+	}
+}
+
+func TestLabelConfigKeepsCooldowns(t *testing.T) {
+	task := demand.Task{
+		ScaleUpCooldown:     30 * time.Second,
+		ScaleDownCooldown:   2 * time.Minute,
+		StabilizationWindow: 5 * time.Minute,
+	}
+
+	// Labels that don't set the cooldowns leave the ones from the API config alone
+	parseLabels(&task, map[string]string{
+		"com.microscaling.priority":          "1",
+		"com.microscaling.scale-up-cooldown": "soon",
+	})
+
+	if task.ScaleUpCooldown != 30*time.Second || task.ScaleDownCooldown != 2*time.Minute {
+		t.Errorf("Cooldowns were changed to %v and %v", task.ScaleUpCooldown, task.ScaleDownCooldown)
+	}
+
+	if task.StabilizationWindow != 5*time.Minute {
+		t.Errorf("Stabilization window was changed to %v", task.StabilizationWindow)
+	}
+}
+
+// tuneRecorder is a target that remembers how it was tuned
+type tuneRecorder struct {
+	target.RemainderTarget
+	pid target.PIDConfig
+	set bool
+}
+
+func (tr *tuneRecorder) Tune(pid target.PIDConfig) {
+	tr.pid = pid
+	tr.set = true
+}
+
+func TestLabelConfigPID(t *testing.T) {
+	tr := &tuneRecorder{}
+	task := demand.Task{Target: tr}
+
+	parseLabels(&task, map[string]string{
+		"com.microscaling.kp":          "0.5",
+		"com.microscaling.KD":          "2",
+		"com.microscaling.ki":          "lots",
+		"com.microscaling.vel-samples": "3",
+	})
+
+	if !tr.set {
+		t.Fatalf("Target wasn't tuned")
+	}
+
+	if *tr.pid.KP != 0.5 || *tr.pid.KD != 2 || *tr.pid.VelSamples != 3 {
+		t.Errorf("Bad PID config %v", tr.pid)
+	}
+
+	if tr.pid.KI != nil || tr.pid.KU != nil || tr.pid.TU != nil {
+		t.Errorf("Unexpected PID config %v", tr.pid)
+	}
+
+	// No need to tune if there aren't any PID labels
+	tr = &tuneRecorder{}
+	task = demand.Task{Target: tr}
+	parseLabels(&task, map[string]string{"com.microscaling.priority": "1"})
+	if tr.set {
+		t.Errorf("Target shouldn't have been tuned")
+	}
+}
+
+func TestLabelConfigSteps(t *testing.T) {
+	task := demand.Task{Target: target.NewQueueLengthTarget(50), Metric: metric.NewNSQMetric("topic", "channel")}
+
+	parseLabels(&task, map[string]string{
+		"com.microscaling.rule-type": "Step",
+		"com.microscaling.steps":     "0-100:-1,100-1000:2,1000-:5",
+	})
+
+	if _, ok := task.Target.(*target.StepTarget); !ok {
+		t.Fatalf("Expected a step target but got %T", task.Target)
+	}
+
+	if task.Target.Delta(500) != 2 {
+		t.Errorf("Expected delta 2 for 500 but was %d", task.Target.Delta(500))
+	}
+
+	// Bad steps leave the target alone
+	task = demand.Task{Target: target.NewQueueLengthTarget(50), Metric: metric.NewNSQMetric("topic", "channel")}
+	parseLabels(&task, map[string]string{
+		"com.microscaling.rule-type": "Step",
+		"com.microscaling.steps":     "0-100:-1,50-:2",
+	})
+
+	if _, ok := task.Target.(*target.QueueLengthTarget); !ok {
+		t.Fatalf("Expected the target to be unchanged but got %T", task.Target)
+	}
+
+	// Rule types without a metric can't use steps
+	task = demand.Task{Target: target.NewRemainderTarget(10), Metric: metric.NewNullMetric()}
+	parseLabels(&task, map[string]string{
+		"com.microscaling.rule-type": "Step",
+		"com.microscaling.steps":     "0-100:-1,100-:2",
+	})
+
+	if _, ok := task.Target.(*target.RemainderTarget); !ok {
+		t.Fatalf("Expected the remainder target to be unchanged but got %T", task.Target)
+	}
 }
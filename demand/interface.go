@@ -3,10 +3,12 @@ package demand
 
 import (
 	"sync"
+	"time"
 
 	"github.com/op/go-logging"
 
 	"github.com/microscaling/microscaling/metric"
+	"github.com/microscaling/microscaling/schedule"
 	"github.com/microscaling/microscaling/target"
 )
 
@@ -14,6 +16,8 @@ import (
 type Tasks struct {
 	Tasks         []*Task
 	MaxContainers int
+	// Total CPU and memory available for all tasks. Zero values mean we only count containers.
+	MaxResources Resources
 	sync.RWMutex
 }
 
@@ -44,6 +48,21 @@ type Task struct {
 	MinContainers int
 	MaxContainers int
 
+	// How long to wait after scaling before we scale up or down again, and how far back we look at the
+	// ideal number of containers before scaling down
+	ScaleUpCooldown     time.Duration
+	ScaleDownCooldown   time.Duration
+	StabilizationWindow time.Duration
+
+	// Times when we override the min and max containers and priority
+	Schedule *schedule.Schedule
+
+	// CPU and memory requested by each container
+	Resources Resources
+
+	// Kind of workload to scale, for schedulers that can scale more than one, e.g. StatefulSet on Kubernetes
+	Kind string
+
 	// The target we're aiming for
 	Target target.Target
 
@@ -52,6 +71,19 @@ type Task struct {
 
 	// Scaling calculation of the ideal number of containers we'd have if there were no other tasks
 	IdealContainers int
+
+	// Set when this task has been removed from the config, and we are scaling it down to 0
+	Draining bool
+
+	// Set when the metric is failing or out of date, so we keep the task at its current scale
+	Holding bool
+
+	// When we last changed demand for this task, and the recent ideals for the stabilization window
+	lastScaled      time.Time
+	recommendations []recommendation
+
+	// Limits from the config, before any schedule overrides
+	configured *scalingLimits
 }
 
 var log = logging.MustGetLogger("mssdemand")
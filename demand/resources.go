@@ -0,0 +1,109 @@
+package demand
+
+// Resources is an amount of CPU and memory. CPU is measured in millicores (so 1000 is one CPU)
+// and memory in bytes. A value of 0 means that resource isn't being accounted for.
+type Resources struct {
+	CPU    int64
+	Memory int64
+}
+
+// IsZero returns true if neither CPU nor memory is being accounted for
+func (r Resources) IsZero() bool {
+	return r.CPU == 0 && r.Memory == 0
+}
+
+// Add returns the total of both sets of resources
+func (r Resources) Add(other Resources) Resources {
+	return Resources{
+		CPU:    r.CPU + other.CPU,
+		Memory: r.Memory + other.Memory,
+	}
+}
+
+// Sub returns the resources left when other is taken away
+func (r Resources) Sub(other Resources) Resources {
+	return Resources{
+		CPU:    r.CPU - other.CPU,
+		Memory: r.Memory - other.Memory,
+	}
+}
+
+// Times returns the resources needed for n containers that each need r
+func (r Resources) Times(n int) Resources {
+	return Resources{
+		CPU:    r.CPU * int64(n),
+		Memory: r.Memory * int64(n),
+	}
+}
+
+// Capacity is the space available for more containers. We always count containers against MaxContainers,
+// and we also count CPU and memory if the deployment has limits for them.
+type Capacity struct {
+	Containers int
+	Resources  Resources
+}
+
+// CheckResources returns the CPU and memory we have that hasn't been requested by any task. This is only
+// meaningful for resources that have a limit set in MaxResources.
+func (tasks *Tasks) CheckResources() Resources {
+	var requested Resources
+	for _, t := range tasks.Tasks {
+		requested = requested.Add(t.Resources.Times(t.Requested))
+	}
+
+	return tasks.MaxResources.Sub(requested)
+}
+
+// AvailableCapacity returns the number of containers and the resources we have space for
+func (tasks *Tasks) AvailableCapacity() Capacity {
+	return Capacity{
+		Containers: tasks.CheckCapacity(),
+		Resources:  tasks.CheckResources(),
+	}
+}
+
+// Fit returns how many containers of this task we have space for. A big task that needs lots of CPU or
+// memory can fit fewer containers into the same capacity than a small one.
+func (tasks *Tasks) Fit(t *Task, c Capacity) int {
+	fit := c.Containers
+
+	if tasks.MaxResources.CPU > 0 && t.Resources.CPU > 0 {
+		fit = minFit(fit, c.Resources.CPU, t.Resources.CPU)
+	}
+
+	if tasks.MaxResources.Memory > 0 && t.Resources.Memory > 0 {
+		fit = minFit(fit, c.Resources.Memory, t.Resources.Memory)
+	}
+
+	return fit
+}
+
+// minFit returns the lower of fit and the number of lots of need we can get out of available
+func minFit(fit int, available int64, need int64) int {
+	n := 0
+	if available > 0 {
+		n = int(available / need)
+	}
+
+	if n < fit {
+		return n
+	}
+
+	return fit
+}
+
+// Claim returns the capacity left after starting n more containers of this task
+func (c Capacity) Claim(t *Task, n int) Capacity {
+	return Capacity{
+		Containers: c.Containers - n,
+		Resources:  c.Resources.Sub(t.Resources.Times(n)),
+	}
+}
+
+// Release returns the capacity we'll have after stopping n containers of this task
+func (c Capacity) Release(t *Task, n int) Capacity {
+	return Capacity{
+		Containers: c.Containers + n,
+		Resources:  c.Resources.Add(t.Resources.Times(n)),
+	}
+}
@@ -0,0 +1,65 @@
+package demand
+
+import (
+	"testing"
+)
+
+func TestCheckResources(t *testing.T) {
+	tt := getTestTasks()
+	tt.MaxResources = Resources{CPU: 4000, Memory: 1000}
+	tt.Tasks[0].Resources = Resources{CPU: 500, Memory: 100}
+	tt.Tasks[1].Resources = Resources{CPU: 1000}
+
+	// 2 x 500m + 2 x 1000m CPU and 2 x 100 memory requested
+	r := tt.CheckResources()
+	if r.CPU != 1000 || r.Memory != 800 {
+		t.Fatalf("Bad resources check: %+v", r)
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		max       Resources
+		resources Resources
+		capacity  Capacity
+		fit       int
+	}{
+		// Only counting containers
+		{capacity: Capacity{Containers: 4}, fit: 4},
+		// Task doesn't say what it needs, so only count containers
+		{max: Resources{CPU: 4000}, capacity: Capacity{Containers: 4, Resources: Resources{CPU: 500}}, fit: 4},
+		// No limit on CPU, so it doesn't matter what the task needs
+		{resources: Resources{CPU: 1000}, capacity: Capacity{Containers: 4}, fit: 4},
+		// Limited by CPU
+		{max: Resources{CPU: 4000}, resources: Resources{CPU: 1000}, capacity: Capacity{Containers: 4, Resources: Resources{CPU: 2500}}, fit: 2},
+		// Limited by memory
+		{max: Resources{CPU: 4000, Memory: 1000}, resources: Resources{CPU: 100, Memory: 300}, capacity: Capacity{Containers: 4, Resources: Resources{CPU: 4000, Memory: 700}}, fit: 2},
+		// Over-committed
+		{max: Resources{Memory: 1000}, resources: Resources{Memory: 300}, capacity: Capacity{Containers: 4, Resources: Resources{Memory: -100}}, fit: 0},
+	}
+
+	for i, test := range tests {
+		tt := getTestTasks()
+		tt.MaxResources = test.max
+		task := &Task{Resources: test.resources}
+
+		fit := tt.Fit(task, test.capacity)
+		if fit != test.fit {
+			t.Errorf("Test %d: expected fit %d but got %d", i, test.fit, fit)
+		}
+	}
+}
+
+func TestClaimRelease(t *testing.T) {
+	task := &Task{Resources: Resources{CPU: 250, Memory: 64}}
+	c := Capacity{Containers: 5, Resources: Resources{CPU: 1000, Memory: 256}}
+
+	claimed := c.Claim(task, 2)
+	if claimed.Containers != 3 || claimed.Resources.CPU != 500 || claimed.Resources.Memory != 128 {
+		t.Fatalf("Bad claim: %+v", claimed)
+	}
+
+	if claimed.Release(task, 2) != c {
+		t.Fatalf("Release should undo claim: %+v", claimed.Release(task, 2))
+	}
+}
@@ -0,0 +1,53 @@
+package demand
+
+import (
+	"time"
+)
+
+// scalingLimits are the settings a schedule can override, as they were configured
+type scalingLimits struct {
+	minContainers int
+	maxContainers int
+	priority      int
+}
+
+// ApplySchedule sets the min and max containers and priority for this task from any schedule windows
+// that are open now, or back to their configured values if none are.
+func (t *Task) ApplySchedule(now time.Time) {
+	if t.Schedule == nil || t.Draining {
+		return
+	}
+
+	if t.configured == nil {
+		t.configured = &scalingLimits{
+			minContainers: t.MinContainers,
+			maxContainers: t.MaxContainers,
+			priority:      t.Priority,
+		}
+	}
+
+	limits := *t.configured
+	o := t.Schedule.Active(now)
+	if o.MinContainers != nil {
+		limits.minContainers = *o.MinContainers
+	}
+	if o.MaxContainers != nil {
+		limits.maxContainers = *o.MaxContainers
+	}
+	if o.Priority != nil {
+		limits.priority = *o.Priority
+	}
+
+	// Don't let the overrides contradict each other
+	if limits.maxContainers < limits.minContainers {
+		limits.maxContainers = limits.minContainers
+	}
+
+	if limits.minContainers != t.MinContainers || limits.maxContainers != t.MaxContainers || limits.priority != t.Priority {
+		log.Infof("Schedule for %s: min containers %d, max containers %d, priority %d", t.Name, limits.minContainers, limits.maxContainers, limits.priority)
+	}
+
+	t.MinContainers = limits.minContainers
+	t.MaxContainers = limits.maxContainers
+	t.Priority = limits.priority
+}
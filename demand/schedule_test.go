@@ -0,0 +1,39 @@
+package demand
+
+import (
+	"testing"
+	"time"
+
+	"github.com/microscaling/microscaling/schedule"
+)
+
+func TestApplySchedule(t *testing.T) {
+	min, max, priority := 4, 2, 1
+	s, err := schedule.New([]schedule.WindowConfig{
+		{Cron: "0 1 * * *", Duration: "4h", Timezone: "UTC", MinContainers: &min, MaxContainers: &max, Priority: &priority},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	task := Task{Name: "batch", MinContainers: 0, MaxContainers: 10, Priority: 3, Schedule: s}
+	night := time.Date(2017, 1, 2, 1, 0, 0, 0, time.UTC)
+
+	task.ApplySchedule(night)
+	if task.MinContainers != 4 || task.MaxContainers != 4 || task.Priority != 1 {
+		t.Fatalf("Expected overrides, with max no lower than min, but got min %d max %d priority %d", task.MinContainers, task.MaxContainers, task.Priority)
+	}
+
+	task.ApplySchedule(night.Add(4 * time.Hour))
+	if task.MinContainers != 0 || task.MaxContainers != 10 || task.Priority != 3 {
+		t.Fatalf("Expected configured values after the window, but got min %d max %d priority %d", task.MinContainers, task.MaxContainers, task.Priority)
+	}
+
+	// Reloaded config takes effect outside the window
+	task.ApplySchedule(night)
+	task.update(&Task{Name: "batch", MinContainers: 1, MaxContainers: 8, Priority: 2, Schedule: s})
+	task.ApplySchedule(night.Add(4 * time.Hour))
+	if task.MinContainers != 1 || task.MaxContainers != 8 || task.Priority != 2 {
+		t.Fatalf("Expected reloaded values after the window, but got min %d max %d priority %d", task.MinContainers, task.MaxContainers, task.Priority)
+	}
+}
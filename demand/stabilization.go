@@ -0,0 +1,73 @@
+package demand
+
+import (
+	"time"
+)
+
+// recommendation is the ideal number of containers we worked out for a task at a particular time
+type recommendation struct {
+	at    time.Time
+	ideal int
+}
+
+// Stabilize records the latest ideal number of containers for this task. If it's lower than we're running
+// we only scale down as far as the highest ideal seen during the stabilization window, so a brief dip in
+// the metric doesn't make us scale down and then straight back up again.
+func (t *Task) Stabilize(ideal int, now time.Time) int {
+	if t.StabilizationWindow <= 0 {
+		t.recommendations = nil
+		return ideal
+	}
+
+	// Forget recommendations from before the window
+	cutoff := now.Add(-t.StabilizationWindow)
+	keep := t.recommendations[:0]
+	for _, r := range t.recommendations {
+		if r.at.After(cutoff) {
+			keep = append(keep, r)
+		}
+	}
+	t.recommendations = append(keep, recommendation{at: now, ideal: ideal})
+
+	if ideal >= t.Running {
+		return ideal
+	}
+
+	stabilized := ideal
+	for _, r := range t.recommendations {
+		if r.ideal > stabilized {
+			stabilized = r.ideal
+		}
+	}
+
+	if stabilized > t.Running {
+		stabilized = t.Running
+	}
+
+	if stabilized != ideal {
+		log.Debugf("Stabilizing %s at %d rather than %d", t.Name, stabilized, ideal)
+	}
+
+	return stabilized
+}
+
+// CanScaleUpAt returns false if we scaled this task less than ScaleUpCooldown ago
+func (t *Task) CanScaleUpAt(now time.Time) bool {
+	return !now.Before(t.lastScaled.Add(t.ScaleUpCooldown))
+}
+
+// CanScaleDownAt returns false if we scaled this task less than ScaleDownCooldown ago
+func (t *Task) CanScaleDownAt(now time.Time) bool {
+	return !now.Before(t.lastScaled.Add(t.ScaleDownCooldown))
+}
+
+// CanPreemptAt returns false if we scaled this task too recently for a higher priority task to take its
+// containers, either within its scale down cooldown or within its stabilization window
+func (t *Task) CanPreemptAt(now time.Time) bool {
+	return t.CanScaleDownAt(now) && !now.Before(t.lastScaled.Add(t.StabilizationWindow))
+}
+
+// Scaled records that we changed the demand for this task, which starts the cooldowns
+func (t *Task) Scaled(now time.Time) {
+	t.lastScaled = now
+}
@@ -0,0 +1,60 @@
+package demand
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStabilize(t *testing.T) {
+	start := time.Now()
+	task := Task{Name: "consumer", Running: 5, StabilizationWindow: 10 * time.Second}
+
+	tests := []struct {
+		after    time.Duration
+		ideal    int
+		expected int
+	}{
+		{after: 0, ideal: 8, expected: 8},                // scaling up isn't held back
+		{after: 2 * time.Second, ideal: 2, expected: 5},  // can't go lower than we're running while 8 is in the window
+		{after: 9 * time.Second, ideal: 3, expected: 5},  // still in the window
+		{after: 11 * time.Second, ideal: 1, expected: 3}, // 8 has dropped out, highest is now 3
+		{after: 30 * time.Second, ideal: 1, expected: 1}, // everything else has dropped out
+		{after: 31 * time.Second, ideal: 4, expected: 4}, // highest in the window
+		{after: 32 * time.Second, ideal: 2, expected: 4},
+	}
+
+	for i, tc := range tests {
+		stabilized := task.Stabilize(tc.ideal, start.Add(tc.after))
+		if stabilized != tc.expected {
+			t.Errorf("%d: expected %d but got %d", i, tc.expected, stabilized)
+		}
+	}
+
+	// No window, no stabilization
+	task.StabilizationWindow = 0
+	if task.Stabilize(1, start.Add(33*time.Second)) != 1 {
+		t.Errorf("Shouldn't stabilize without a window")
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	now := time.Now()
+	task := Task{Name: "consumer", ScaleUpCooldown: 10 * time.Second, ScaleDownCooldown: time.Minute}
+
+	if !task.CanScaleUpAt(now) || !task.CanScaleDownAt(now) {
+		t.Fatalf("Should be able to scale if we've never scaled")
+	}
+
+	task.Scaled(now)
+	if task.CanScaleUpAt(now.Add(5*time.Second)) || task.CanScaleDownAt(now.Add(5*time.Second)) {
+		t.Fatalf("Shouldn't scale during cooldown")
+	}
+
+	if !task.CanScaleUpAt(now.Add(10*time.Second)) || task.CanScaleDownAt(now.Add(10*time.Second)) {
+		t.Fatalf("Should be able to scale up, but not down, after 10s")
+	}
+
+	if !task.CanScaleDownAt(now.Add(time.Minute)) {
+		t.Fatalf("Should be able to scale down after a minute")
+	}
+}
@@ -3,6 +3,7 @@ package demand
 import (
 	"reflect"
 
+	"github.com/microscaling/microscaling/metric"
 	"github.com/microscaling/microscaling/target"
 )
 
@@ -13,6 +14,25 @@ func (t *Task) IsRemainder() bool {
 	return ruleType == remainderType
 }
 
+// EffectiveMaxContainers is the most containers we should run for this task. This is the configured max,
+// unless the metric knows there's no point running that many. We always allow the minimum though.
+func (t *Task) EffectiveMaxContainers() int {
+	max := t.MaxContainers
+
+	if l, ok := t.Metric.(metric.ContainerLimiter); ok {
+		limit := l.MaxContainers()
+		if limit > 0 && limit < max {
+			max = limit
+		}
+	}
+
+	if max < t.MinContainers {
+		max = t.MinContainers
+	}
+
+	return max
+}
+
 // ScaleUpCount tells us how many containers to scale up by
 // Call this after IdealContainers has been updated
 func (t *Task) ScaleUpCount() (delta int) {
@@ -35,8 +55,9 @@ func (t *Task) ScaleUpCount() (delta int) {
 	}
 
 	// But make sure this won't exceed the maximum
-	if t.Requested+delta > t.MaxContainers {
-		delta = t.MaxContainers - t.Requested
+	max := t.EffectiveMaxContainers()
+	if t.Requested+delta > max {
+		delta = max - t.Requested
 		log.Debugf("Can't exceed max -> delta %d", delta)
 	}
 
@@ -71,8 +92,9 @@ func (t *Task) ScaleDownCount() (delta int) {
 	}
 
 	// Make sure this won't exceed the maximum
-	if t.Requested+delta > t.MaxContainers {
-		delta = t.MaxContainers - t.Requested
+	max := t.EffectiveMaxContainers()
+	if t.Requested+delta > max {
+		delta = max - t.Requested
 		log.Debugf("Can't exceed max -> delta %d", delta)
 	}
 
@@ -86,7 +108,7 @@ func (t *Task) ScaleDownCount() (delta int) {
 
 // CanScaleDown returns the number we could scale down by
 func (t *Task) CanScaleDown() int {
-	if !t.IsScalable {
+	if !t.IsScalable || t.Draining || t.Holding {
 		return 0
 	}
 
@@ -95,3 +117,39 @@ func (t *Task) CanScaleDown() int {
 	}
 	return t.Requested - t.MinContainers
 }
+
+// update takes on the config from a newly loaded version of this task, while keeping the scheduler
+// state and (where the target type hasn't changed) the target's state
+func (t *Task) update(latest *Task) {
+	t.FamilyName = latest.FamilyName
+	t.Image = latest.Image
+	t.Command = latest.Command
+	t.PublishAllPorts = latest.PublishAllPorts
+	t.NetworkMode = latest.NetworkMode
+	t.Env = latest.Env
+
+	t.IsScalable = latest.IsScalable
+	t.Priority = latest.Priority
+	t.MaxDelta = latest.MaxDelta
+	t.MinContainers = latest.MinContainers
+	t.MaxContainers = latest.MaxContainers
+	t.ScaleUpCooldown = latest.ScaleUpCooldown
+	t.ScaleDownCooldown = latest.ScaleDownCooldown
+	t.StabilizationWindow = latest.StabilizationWindow
+	t.Schedule = latest.Schedule
+	t.configured = nil
+	t.Resources = latest.Resources
+
+	// The scheduler set up the task for its kind of workload when it started, so we can't switch to another
+	if latest.Kind != t.Kind {
+		log.Errorf("Ignoring change of kind for %s from %q to %q, restart to scale a different kind of workload", t.Name, t.Kind, latest.Kind)
+	}
+
+	if r, ok := t.Target.(target.Reconfigurable); !ok || !r.Reconfigure(latest.Target) {
+		log.Debugf("Replacing target for %s", t.Name)
+		t.Target = latest.Target
+	}
+
+	t.Metric = latest.Metric
+	t.Draining = false
+}
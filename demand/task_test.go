@@ -149,3 +149,53 @@ func TestCanScaleDown(t *testing.T) {
 		t.Fatalf("Can't scale down if requested is already at minimum")
 	}
 }
+
+// limitedMetric is a toy metric that also limits the number of containers
+type limitedMetric struct {
+	metric.ToyMetric
+	limit int
+}
+
+func (l *limitedMetric) MaxContainers() int {
+	return l.limit
+}
+
+func TestEffectiveMaxContainers(t *testing.T) {
+	_, testTask := getTestTask()
+
+	if testTask.EffectiveMaxContainers() != 5 {
+		t.Fatalf("Expected configured max without a limit from the metric")
+	}
+
+	lm := &limitedMetric{}
+	testTask.Metric = lm
+	if testTask.EffectiveMaxContainers() != 5 {
+		t.Fatalf("A limit of 0 means there's no limit")
+	}
+
+	lm.limit = 3
+	if testTask.EffectiveMaxContainers() != 3 {
+		t.Fatalf("Expected the metric to limit max containers")
+	}
+
+	lm.limit = 8
+	if testTask.EffectiveMaxContainers() != 5 {
+		t.Fatalf("Metric shouldn't raise max containers")
+	}
+
+	lm.limit = 1
+	testTask.MinContainers = 2
+	if testTask.EffectiveMaxContainers() != 2 {
+		t.Fatalf("Should always allow the minimum")
+	}
+
+	// Scale up should stop at the limit
+	lm.limit = 3
+	lm.SettableCurrent = 100
+	testTask.MinContainers = 1
+	testTask.Requested = 2
+	testTask.IdealContainers = 5
+	if testTask.ScaleUpCount() != 1 {
+		t.Fatalf("Unexpected scale up count %d with limit of 3", testTask.ScaleUpCount())
+	}
+}
@@ -21,10 +21,9 @@ func (tasks *Tasks) Exited() (done bool) {
 	return done
 }
 
-// CheckCapacity returns number of containers we have space for
+// CheckCapacity returns number of containers we have space for. This is only the container count, Fit also
+// looks at CPU and memory.
 func (tasks *Tasks) CheckCapacity() int {
-	// TODO!! For now we are simply going to say there is a maximum total number of containers this deployment can handle
-	// TODO!! It should really look at the available CPU / mem / bw in / out
 	totalRequested := 0
 	for _, t := range tasks.Tasks {
 		totalRequested += t.Requested
@@ -33,6 +32,62 @@ func (tasks *Tasks) CheckCapacity() int {
 	return tasks.MaxContainers - totalRequested
 }
 
+// Reconcile updates the tasks we are managing to match the latest config. Tasks we already know about
+// are updated in place so we keep their state, new tasks are added, and tasks that are no longer
+// configured are drained down to 0. New tasks should already have been initialized with the scheduler.
+// Returns true if we have started draining any tasks.
+func (tasks *Tasks) Reconcile(latest []*Task, maxContainers int) (draining bool) {
+	tasks.Lock()
+	defer tasks.Unlock()
+
+	tasks.MaxContainers = maxContainers
+	configured := make(map[string]bool, len(latest))
+
+	for _, l := range latest {
+		configured[l.Name] = true
+
+		existing, err := tasks.GetTask(l.Name)
+		if err != nil {
+			log.Infof("Adding task %s", l.Name)
+			tasks.Tasks = append(tasks.Tasks, l)
+			continue
+		}
+
+		log.Debugf("Updating task %s", l.Name)
+		existing.update(l)
+	}
+
+	for _, t := range tasks.Tasks {
+		if !configured[t.Name] && !t.Draining {
+			log.Infof("Draining task %s", t.Name)
+			t.Draining = true
+			t.MinContainers = 0
+			t.Demand = 0
+			draining = true
+		}
+	}
+
+	return draining
+}
+
+// RemoveDrained stops managing any tasks that have been drained down to 0
+func (tasks *Tasks) RemoveDrained() {
+	tasks.Lock()
+	defer tasks.Unlock()
+
+	var remaining []*Task
+	for _, t := range tasks.Tasks {
+		if t.Draining && t.Running == 0 && t.Requested == 0 {
+			log.Infof("Removed task %s", t.Name)
+			continue
+		}
+
+		remaining = append(remaining, t)
+	}
+
+	tasks.Tasks = remaining
+}
+
 // implements sort.Interface tasks based on priority
 type byPriority []*Task
 
@@ -1,7 +1,10 @@
 package demand
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/microscaling/microscaling/target"
 )
 
 func getTestTasks() Tasks {
@@ -93,3 +96,93 @@ func TestExited(t *testing.T) {
 		t.Fatal("Unexpectedly not exited")
 	}
 }
+
+func TestReconcile(t *testing.T) {
+	tt := getTestTasks()
+	q := target.NewQueueLengthTarget(10)
+	tt.Tasks[1].Target = q
+	tt.Tasks[1].MaxContainers = 4
+
+	latest := []*Task{
+		&Task{
+			Name:          "One",
+			Priority:      3,
+			MaxContainers: 8,
+			Kind:          "StatefulSet",
+			Target:        target.NewQueueLengthTarget(20),
+		},
+		&Task{
+			Name:   "Two",
+			Target: target.NewRemainderTarget(5),
+		},
+		&Task{
+			Name:   "Three",
+			Target: target.NewRemainderTarget(5),
+		},
+	}
+
+	if !tt.Reconcile(latest, 20) {
+		t.Fatal("Expected a task to be draining")
+	}
+
+	if tt.MaxContainers != 20 {
+		t.Fatalf("Max containers not updated")
+	}
+
+	if len(tt.Tasks) != 4 {
+		t.Fatalf("Expected 4 tasks but there are %d", len(tt.Tasks))
+	}
+
+	one, _ := tt.GetTask("One")
+	if one.Priority != 3 || one.MaxContainers != 8 {
+		t.Fatalf("Task not updated: %v", one)
+	}
+	if one.Target != q {
+		t.Fatalf("Queue target should have been kept so we don't lose its state")
+	}
+	if one.Running != 2 || one.Requested != 2 {
+		t.Fatalf("Scheduler state should not have changed")
+	}
+	if one.Kind != "" {
+		t.Fatalf("Kind shouldn't change on reload but is now %s", one.Kind)
+	}
+
+	two, _ := tt.GetTask("Two")
+	if reflect.TypeOf(two.Target) != reflect.TypeOf(&target.RemainderTarget{}) {
+		t.Fatalf("Target should have been replaced when its type changed")
+	}
+
+	zero, _ := tt.GetTask("Zero")
+	if !zero.Draining || zero.Demand != 0 {
+		t.Fatalf("Removed task should be draining")
+	}
+
+	three, _ := tt.GetTask("Three")
+	if three.Draining {
+		t.Fatalf("New task should not be draining")
+	}
+
+	// Draining again shouldn't report anything new
+	if tt.Reconcile(latest, 20) {
+		t.Fatal("Expected no new tasks to be draining")
+	}
+
+	tt.RemoveDrained()
+	if len(tt.Tasks) != 4 {
+		t.Fatalf("Shouldn't remove a task until it has drained")
+	}
+
+	zero.Running = 0
+	zero.Requested = 0
+	tt.RemoveDrained()
+	if len(tt.Tasks) != 3 {
+		t.Fatalf("Expected drained task to be removed")
+	}
+
+	// Adding a task back in stops it draining
+	one.Draining = true
+	tt.Reconcile(latest, 20)
+	if one.Draining {
+		t.Fatalf("Task should have stopped draining")
+	}
+}
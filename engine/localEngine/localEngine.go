@@ -14,6 +14,7 @@ const constGetDemandSleep = 500
 
 // LocalEngine calculates demand locally
 type LocalEngine struct {
+	metricStaleAfter time.Duration
 }
 
 // compile-time assert that we implement the right interface
@@ -21,9 +22,12 @@ var _ engine.Engine = (*LocalEngine)(nil)
 
 var log = logging.MustGetLogger("mssengine")
 
-// NewEngine initializes the local engine
-func NewEngine() *LocalEngine {
-	de := LocalEngine{}
+// NewEngine initializes the local engine. We won't scale a task if its metric hasn't been read successfully
+// for longer than metricStaleAfter (0 means we don't check).
+func NewEngine(metricStaleAfter time.Duration) *LocalEngine {
+	de := LocalEngine{
+		metricStaleAfter: metricStaleAfter,
+	}
 	return &de
 }
 
@@ -38,17 +42,22 @@ func (de *LocalEngine) GetDemand(tasks *demand.Tasks, demandUpdate chan struct{}
 		log.Debug("Getting demand")
 
 		for _, task := range tasks.Tasks {
+			if task.Draining {
+				continue
+			}
+
 			gettingMetrics.Add(1)
 			go func(task *demand.Task) {
 				defer gettingMetrics.Done()
 				log.Debugf("Getting metric for %s", task.Name)
-				task.Metric.UpdateCurrent()
+				err := task.Metric.UpdateCurrent()
+				task.Holding = metricHold(task, err, time.Now(), de.metricStaleAfter)
 			}(task)
 		}
 
 		gettingMetrics.Wait()
 
-		demandChanged := scalingCalculation(tasks)
+		demandChanged := scalingCalculation(tasks, time.Now())
 
 		tasks.Unlock()
 		if demandChanged {
@@ -57,6 +66,23 @@ func (de *LocalEngine) GetDemand(tasks *demand.Tasks, demandUpdate chan struct{}
 	}
 }
 
+// metricHold returns true if we can't trust the metric for a task, because we failed to read it or it's out
+// of date. A metric we can't read could look like an empty queue, and we don't want to scale down because of that.
+func metricHold(task *demand.Task, err error, now time.Time, staleAfter time.Duration) bool {
+	if err != nil {
+		log.Errorf("Holding %s at its current scale: %v", task.Name, err)
+		return true
+	}
+
+	updated := task.Metric.Updated()
+	if staleAfter > 0 && now.Sub(updated) > staleAfter {
+		log.Errorf("Holding %s at its current scale: metric last updated at %v", task.Name, updated)
+		return true
+	}
+
+	return false
+}
+
 // StopDemand is called when we want to shut down
 func (de *LocalEngine) StopDemand(demandUpdate chan struct{}) {
 	close(demandUpdate)
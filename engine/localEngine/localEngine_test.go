@@ -0,0 +1,368 @@
+package localEngine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/metric"
+	"github.com/microscaling/microscaling/schedule"
+	"github.com/microscaling/microscaling/scheduler/toy"
+	"github.com/microscaling/microscaling/target"
+)
+
+func TestMetricHold(t *testing.T) {
+	now := time.Now()
+	m := metric.NewToyMetric()
+	task := &demand.Task{Name: "consumer", Metric: m}
+
+	tests := []struct {
+		name       string
+		err        error
+		updated    time.Time
+		staleAfter time.Duration
+		hold       bool
+	}{
+		{name: "fresh", updated: now, staleAfter: time.Minute, hold: false},
+		{name: "error", err: errors.New("Connection refused"), updated: now, staleAfter: time.Minute, hold: true},
+		{name: "stale", updated: now.Add(-2 * time.Minute), staleAfter: time.Minute, hold: true},
+		{name: "not checking", updated: now.Add(-2 * time.Minute), hold: false},
+	}
+
+	for _, tc := range tests {
+		m.SettableUpdated = tc.updated
+		if metricHold(task, tc.err, now, tc.staleAfter) != tc.hold {
+			t.Errorf("%s: expected hold %t", tc.name, tc.hold)
+		}
+	}
+}
+
+func TestScalingCalculationHolding(t *testing.T) {
+	m := metric.NewToyMetric()
+
+	tasks := &demand.Tasks{MaxContainers: 10}
+	tasks.Tasks = []*demand.Task{
+		&demand.Task{
+			Name:          "consumer",
+			IsScalable:    true,
+			Priority:      1,
+			MinContainers: 1,
+			MaxContainers: 10,
+			MaxDelta:      10,
+			Requested:     5,
+			Running:       5,
+			Target:        target.NewSimpleQueueLengthTarget(50),
+			Metric:        m,
+			Holding:       true,
+		},
+		&demand.Task{
+			Name:          "background",
+			IsScalable:    true,
+			Priority:      2,
+			MaxContainers: 10,
+			MaxDelta:      10,
+			Target:        target.NewRemainderTarget(10),
+			Metric:        metric.NewNullMetric(),
+		},
+	}
+
+	// An empty queue would normally scale the consumer down, but we can't trust the metric
+	m.SettableCurrent = 0
+	scalingCalculation(tasks, time.Now())
+
+	consumer, _ := tasks.GetTask("consumer")
+	if consumer.Demand != 5 {
+		t.Fatalf("Expected consumer to stay at 5 but demand is %d", consumer.Demand)
+	}
+
+	// Other tasks can still use the capacity that's left
+	background, _ := tasks.GetTask("background")
+	if background.Demand != 5 {
+		t.Fatalf("Expected background to use the remaining 5 containers but demand is %d", background.Demand)
+	}
+
+	// Once we can trust it again we scale down
+	consumer.Holding = false
+	background.Requested = background.Demand
+	background.Running = background.Demand
+	scalingCalculation(tasks, time.Now())
+	if consumer.Demand != 4 {
+		t.Fatalf("Expected consumer to scale down to 4 but demand is %d", consumer.Demand)
+	}
+}
+
+// feedbackTarget always wants the same delta, and records what we tell it was applied
+type feedbackTarget struct {
+	target.Target
+	delta     int
+	requested int
+	applied   int
+	calls     int
+}
+
+func (f *feedbackTarget) Delta(int) int { return f.delta }
+
+func (f *feedbackTarget) Applied(requested int, applied int) {
+	f.requested = requested
+	f.applied = applied
+	f.calls++
+}
+
+func TestScalingCalculationFeedback(t *testing.T) {
+	ft := &feedbackTarget{Target: target.NewQueueLengthTarget(10), delta: 5}
+	m := metric.NewToyMetric()
+	m.SettableCurrent = 30
+
+	tasks := &demand.Tasks{MaxContainers: 10}
+	tasks.Tasks = []*demand.Task{
+		&demand.Task{
+			Name:          "consumer",
+			IsScalable:    true,
+			Priority:      1,
+			MaxContainers: 6,
+			MaxDelta:      10,
+			Requested:     3,
+			Running:       3,
+			Target:        ft,
+			Metric:        m,
+		},
+	}
+
+	// We can only go up to the max of 6
+	scalingCalculation(tasks, time.Now())
+	if ft.calls != 1 || ft.requested != 5 || ft.applied != 3 {
+		t.Fatalf("Expected feedback that 3 of 5 were applied but got %d of %d (%d calls)", ft.applied, ft.requested, ft.calls)
+	}
+
+	// No feedback while a scale operation is in progress
+	tasks.Tasks[0].Requested = 6
+	scalingCalculation(tasks, time.Now())
+	if ft.calls != 1 {
+		t.Fatalf("Didn't expect feedback while scaling")
+	}
+}
+
+// scaleAt runs the scaling calculation at a particular time, and lets the toy scheduler do what we asked
+func scaleAt(t *testing.T, tasks *demand.Tasks, now time.Time) {
+	s := toy.NewScheduler()
+	scalingCalculation(tasks, now)
+
+	if err := s.StopStartTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if err := s.CountAllTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
+
+func cooldownTasks(m metric.Metric) *demand.Tasks {
+	tasks := &demand.Tasks{MaxContainers: 10}
+	tasks.Tasks = []*demand.Task{
+		&demand.Task{
+			Name:          "consumer",
+			IsScalable:    true,
+			Priority:      1,
+			MinContainers: 1,
+			MaxContainers: 10,
+			MaxDelta:      10,
+			Demand:        5,
+			Requested:     5,
+			Running:       5,
+			Target:        target.NewSimpleQueueLengthTarget(50),
+			Metric:        m,
+		},
+	}
+
+	return tasks
+}
+
+func TestScalingCalculationCooldown(t *testing.T) {
+	m := metric.NewToyMetric()
+	tasks := cooldownTasks(m)
+	consumer := tasks.Tasks[0]
+	consumer.ScaleUpCooldown = 2 * time.Second
+	consumer.ScaleDownCooldown = 10 * time.Second
+	start := time.Now()
+
+	steps := []struct {
+		after    time.Duration
+		queue    int
+		expected int
+	}{
+		{after: 0, queue: 100, expected: 6},
+		{after: 500 * time.Millisecond, queue: 0, expected: 6}, // too soon to scale down
+		{after: time.Second, queue: 100, expected: 6},          // too soon to scale up
+		{after: 3 * time.Second, queue: 100, expected: 7},      // can scale up again
+		{after: 12 * time.Second, queue: 0, expected: 7},       // still too soon after scaling up to scale down
+		{after: 13 * time.Second, queue: 0, expected: 6},       // cooled down
+		{after: 14 * time.Second, queue: 100, expected: 6},     // too soon to scale back up
+	}
+
+	for i, step := range steps {
+		m.SettableCurrent = step.queue
+		scaleAt(t, tasks, start.Add(step.after))
+		if consumer.Running != step.expected {
+			t.Fatalf("Step %d: expected %d running but have %d", i, step.expected, consumer.Running)
+		}
+	}
+}
+
+func TestScalingCalculationPreemptCooldown(t *testing.T) {
+	m := metric.NewToyMetric()
+	tasks := cooldownTasks(m)
+	consumer := tasks.Tasks[0]
+	background := &demand.Task{
+		Name:              "background",
+		IsScalable:        true,
+		Priority:          2,
+		MaxContainers:     10,
+		MaxDelta:          10,
+		Demand:            5,
+		Requested:         5,
+		Running:           5,
+		ScaleDownCooldown: 10 * time.Second,
+		Target:            target.NewRemainderTarget(10),
+		Metric:            metric.NewNullMetric(),
+	}
+	tasks.Tasks = append(tasks.Tasks, background)
+
+	// The background task has just scaled up to fill the space
+	start := time.Now()
+	background.Scaled(start)
+
+	steps := []struct {
+		after      time.Duration
+		consumer   int
+		background int
+	}{
+		{after: time.Second, consumer: 5, background: 5},      // too soon to take containers from the background task
+		{after: 11 * time.Second, consumer: 5, background: 4}, // cooled down, so make space
+		{after: 12 * time.Second, consumer: 6, background: 4}, // and use it once it's free
+	}
+
+	m.SettableCurrent = 100
+	for i, step := range steps {
+		scaleAt(t, tasks, start.Add(step.after))
+		if consumer.Running != step.consumer || background.Running != step.background {
+			t.Fatalf("Step %d: expected consumer %d and background %d but have %d and %d", i, step.consumer, step.background, consumer.Running, background.Running)
+		}
+	}
+}
+
+func TestScalingCalculationStabilization(t *testing.T) {
+	m := metric.NewToyMetric()
+	tasks := cooldownTasks(m)
+	consumer := tasks.Tasks[0]
+	consumer.StabilizationWindow = 5 * time.Second
+	start := time.Now()
+
+	steps := []struct {
+		after    time.Duration
+		queue    int
+		expected int
+	}{
+		{after: 0, queue: 100, expected: 6},
+		{after: time.Second, queue: 0, expected: 6},     // we wanted 6 within the window
+		{after: 4 * time.Second, queue: 0, expected: 6}, // still within the window
+		{after: 6 * time.Second, queue: 0, expected: 5}, // only lower recommendations left in the window
+		{after: 7 * time.Second, queue: 0, expected: 5}, // we wanted 5 a second ago
+	}
+
+	for i, step := range steps {
+		m.SettableCurrent = step.queue
+		scaleAt(t, tasks, start.Add(step.after))
+		if consumer.Running != step.expected {
+			t.Fatalf("Step %d: expected %d running but have %d", i, step.expected, consumer.Running)
+		}
+	}
+}
+
+func TestScalingCalculationSchedule(t *testing.T) {
+	min, containers := 3, 2
+	s, err := schedule.New([]schedule.WindowConfig{
+		{Cron: "0 9 * * 1-5", Duration: "8h", Timezone: "UTC", MinContainers: &min},
+		{Cron: "0 1 * * *", Duration: "4h", Timezone: "UTC", Containers: &containers},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	m := metric.NewToyMetric()
+	tasks := cooldownTasks(m)
+	web := tasks.Tasks[0]
+	web.Name = "web"
+	web.MinContainers = 1
+	web.Demand, web.Requested, web.Running = 1, 1, 1
+	web.Schedule = s
+
+	tasks.Tasks = append(tasks.Tasks, &demand.Task{
+		Name:          "batch",
+		IsScalable:    true,
+		Priority:      2,
+		MaxContainers: 5,
+		MaxDelta:      5,
+		Target:        target.NewScheduleTarget(s),
+		Metric:        metric.NewNullMetric(),
+	})
+	batch := tasks.Tasks[1]
+
+	// Monday 2 January 2017
+	monday := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		at    time.Time
+		web   int
+		batch int
+	}{
+		{at: monday, web: 1, batch: 0},
+		{at: monday.Add(time.Hour), web: 1, batch: 2},      // batch window
+		{at: monday.Add(5 * time.Hour), web: 1, batch: 0},  // batch done
+		{at: monday.Add(9 * time.Hour), web: 3, batch: 0},  // business hours
+		{at: monday.Add(17 * time.Hour), web: 2, batch: 0}, // the queue is empty so we can scale down again
+	}
+
+	for i, step := range steps {
+		scaleAt(t, tasks, step.at)
+		if web.Running != step.web || batch.Running != step.batch {
+			t.Fatalf("Step %d: expected web %d and batch %d but have %d and %d", i, step.web, step.batch, web.Running, batch.Running)
+		}
+	}
+
+	if web.MinContainers != 1 {
+		t.Fatalf("Expected web to go back to its configured min containers but is %d", web.MinContainers)
+	}
+}
+
+// rateMetric is a toy queue that can also tell us how fast it's being worked through
+type rateMetric struct {
+	*metric.ToyMetric
+	rate float64
+}
+
+func (r *rateMetric) DequeueRate() (float64, time.Time, bool) {
+	return r.rate, time.Time{}, r.rate > 0
+}
+
+func TestScalingCalculationDequeueRate(t *testing.T) {
+	m := &rateMetric{ToyMetric: metric.NewToyMetric()}
+	m.SettableCurrent = 600
+	tasks := cooldownTasks(m)
+	consumer := tasks.Tasks[0]
+	consumer.Target = target.NewDrainTimeTarget(time.Minute)
+
+	// Without a rate we don't know how fast each container is, and the queue isn't growing
+	start := time.Now()
+	scaleAt(t, tasks, start)
+	if consumer.Running != 5 {
+		t.Fatalf("Expected 5 running but have %d", consumer.Running)
+	}
+
+	// 10 a second each, so we need another container to keep up and drain 10 a second
+	m.rate = 50
+	scaleAt(t, tasks, start.Add(time.Second))
+	if consumer.Running != 6 {
+		t.Fatalf("Expected 6 running but have %d", consumer.Running)
+	}
+}
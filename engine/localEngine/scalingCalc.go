@@ -1,26 +1,63 @@
 package localEngine
 
 import (
+	"time"
+
 	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/metric"
+	"github.com/microscaling/microscaling/target"
 )
 
-func scalingCalculation(tasks *demand.Tasks) (demandChanged bool) {
+func scalingCalculation(tasks *demand.Tasks, now time.Time) (demandChanged bool) {
 	delta := 0
 	demandChanged = false
 
+	// How much each task was actually scaled by, so we can tell targets if they didn't get what they asked for
+	applied := make(map[*demand.Task]int, len(tasks.Tasks))
+
 	// Work out the ideal scale for all the services
 	for _, t := range tasks.Tasks {
-		t.IdealContainers = t.Running + t.Target.Delta(t.Metric.Current())
+		t.ApplySchedule(now)
+
+		if t.Draining {
+			// Demand has already been set to 0 for tasks that are draining
+			continue
+		}
+
+		if t.Holding {
+			// We can't trust the metric, so stay where we are
+			t.IdealContainers = t.Requested
+			t.Demand = t.Requested
+			continue
+		}
+
+		if o, ok := t.Target.(target.Observer); ok {
+			o.Observe(t.Running)
+		}
+
+		if tm, ok := t.Target.(target.Timed); ok {
+			tm.At(now)
+		}
+
+		if ro, ok := t.Target.(target.RateObserver); ok {
+			if r, ok := t.Metric.(metric.RateReporter); ok {
+				if rate, since, ok := r.DequeueRate(); ok {
+					ro.ObserveRate(rate, since)
+				}
+			}
+		}
+
+		t.IdealContainers = t.Stabilize(t.Running+t.Target.Delta(t.Metric.Current()), now)
 		log.Debugf("  [scale] ideal for %s priority %d would be %d. %d running, %d requested", t.Name, t.Priority, t.IdealContainers, t.Running, t.Requested)
 	}
 
-	available := tasks.CheckCapacity()
-	log.Debugf("  [scale] available space: %d", available)
+	available := tasks.AvailableCapacity()
+	log.Debugf("  [scale] available space: %d containers, CPU %dm, memory %d", available.Containers, available.Resources.CPU, available.Resources.Memory)
 
 	// Look for services we could scale down, in reverse priority order
 	tasks.PrioritySort(true)
 	for _, t := range tasks.Tasks {
-		if !t.IsScalable || t.Requested == t.MinContainers {
+		if !t.IsScalable || t.Draining || t.Holding || t.Requested == t.MinContainers {
 			// Can't scale this service down
 			continue
 		}
@@ -31,12 +68,18 @@ func scalingCalculation(tasks *demand.Tasks) (demandChanged bool) {
 			continue
 		}
 
+		if !t.CanScaleDownAt(now) {
+			log.Debugf("  [scale] %s scaled too recently to scale down", t.Name)
+			continue
+		}
+
 		// For scaling down, delta should be negative
 		delta = t.ScaleDownCount()
 		if delta < 0 {
 			t.Demand = t.Running + delta
+			applied[t] = delta
 			demandChanged = true
-			available += (-delta)
+			available = available.Release(t, -delta)
 			log.Debugf("  [scale] scaling %s down by %d", t.Name, delta)
 		}
 	}
@@ -44,7 +87,7 @@ func scalingCalculation(tasks *demand.Tasks) (demandChanged bool) {
 	// Now look for tasks we need to scale up
 	tasks.PrioritySort(false)
 	for p, t := range tasks.Tasks {
-		if !t.IsScalable {
+		if !t.IsScalable || t.Draining || t.Holding {
 			continue
 		}
 
@@ -54,56 +97,114 @@ func scalingCalculation(tasks *demand.Tasks) (demandChanged bool) {
 			continue
 		}
 
+		if !t.CanScaleUpAt(now) {
+			log.Debugf("  [scale] %s scaled too recently to scale up", t.Name)
+			continue
+		}
+
 		delta = t.ScaleUpCount()
 		if delta <= 0 {
 			continue
 		}
 
-		log.Debugf("  [scale]  would like to scale up %s by %d - available %d", t.Name, delta, available)
+		// Big tasks take up more of the available resources than small ones
+		fit := tasks.Fit(t, available)
+		log.Debugf("  [scale]  would like to scale up %s by %d - space for %d", t.Name, delta, fit)
 
-		if available < delta {
+		if fit < delta {
 			// If this is a task that fills the remainder, there's no need to exceed capacity
 			if !t.IsRemainder() {
-				log.Debugf("  [scale] looking for %d additional capacity by scaling down:", delta-available)
+				log.Debugf("  [scale] looking for space for %d more by scaling down:", delta-fit)
 				index := len(tasks.Tasks)
 				freedCapacity := available
-				for index > p+1 && freedCapacity < delta {
+				for index > p+1 && tasks.Fit(t, freedCapacity) < delta {
 					// Kill off lower priority services if we need to
 					index--
 					lowerPriorityService := tasks.Tasks[index]
-					if lowerPriorityService.Priority > t.Priority {
+					if lowerPriorityService.Priority > t.Priority && canPreempt(lowerPriorityService, applied, now) {
 						log.Debugf("  [scale] looking for capacity from %s: running %d requested %d demand %d", lowerPriorityService.Name, lowerPriorityService.Running, lowerPriorityService.Requested, lowerPriorityService.Demand)
-						scaleDownBy := lowerPriorityService.CanScaleDown()
-						if scaleDownBy > 0 {
-							if scaleDownBy > (delta - freedCapacity) {
-								scaleDownBy = delta - freedCapacity
-							}
+						// Only scale down as many as we need to make space
+						canScaleDown := lowerPriorityService.CanScaleDown()
+						scaleDownBy := 0
+						for scaleDownBy < canScaleDown && tasks.Fit(t, freedCapacity) < delta {
+							freedCapacity = freedCapacity.Release(lowerPriorityService, 1)
+							scaleDownBy++
+						}
 
+						if scaleDownBy > 0 {
 							lowerPriorityService.Demand = lowerPriorityService.Running - scaleDownBy
+							applied[lowerPriorityService] = -scaleDownBy
 							demandChanged = true
 							log.Debugf("  [scale] Service %s priority %d scaling down %d", lowerPriorityService.Name, lowerPriorityService.Priority, -scaleDownBy)
-							freedCapacity = freedCapacity + scaleDownBy
 						}
 					}
 				}
 			}
 
 			// We might still not have enough capacity and we haven't waited for scale down to complete, so just scale up what's available now
-			delta = available
+			delta = fit
 			log.Debugf("  [scale] Can only scale %s by %d", t.Name, delta)
 		}
 
 		if delta > 0 {
 			demandChanged = true
-			available -= delta
-			if t.Demand >= t.MaxContainers {
-				log.Errorf("  [scale ] Limiting %s to its configured max %d", t.Name, t.MaxContainers)
-				t.Demand = t.MaxContainers
+			available = available.Claim(t, delta)
+			if max := t.EffectiveMaxContainers(); t.Demand >= max {
+				log.Errorf("  [scale ] Limiting %s to its max %d", t.Name, max)
+				t.Demand = max
 			} else {
 				log.Debugf("  [scale] Service %s scaling up %d", t.Name, delta)
 				t.Demand = t.Running + delta
 			}
+			applied[t] = t.Demand - t.Running
+		}
+	}
+
+	for t, d := range applied {
+		if d != 0 {
+			t.Scaled(now)
 		}
 	}
+
+	feedback(tasks, applied)
 	return demandChanged
 }
+
+// canPreempt returns false if we shouldn't take containers from a lower priority task, for the same reasons we
+// wouldn't scale it down: it's in the middle of scaling, we've already scaled it this time, or it scaled too
+// recently.
+func canPreempt(t *demand.Task, applied map[*demand.Task]int, now time.Time) bool {
+	if !t.IsScalable || t.Draining || t.Holding {
+		return false
+	}
+
+	if t.Running != t.Requested {
+		log.Debugf("  [scale] can't take capacity from %s while it's scaling: running %d, requested %d", t.Name, t.Running, t.Requested)
+		return false
+	}
+
+	if _, ok := applied[t]; ok {
+		return false
+	}
+
+	if !t.CanPreemptAt(now) {
+		log.Debugf("  [scale] %s scaled too recently to take capacity from", t.Name)
+		return false
+	}
+
+	return true
+}
+
+// feedback lets targets know how much of the delta they asked for was applied. We only do this for tasks
+// we made a decision about this time, not ones that are waiting for a scale operation to complete.
+func feedback(tasks *demand.Tasks, applied map[*demand.Task]int) {
+	for _, t := range tasks.Tasks {
+		if !t.IsScalable || t.Draining || t.Holding || t.Running != t.Requested {
+			continue
+		}
+
+		if f, ok := t.Target.(target.Feedback); ok {
+			f.Applied(t.IdealContainers-t.Running, applied[t])
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package forecast
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microscaling/microscaling/target"
+)
+
+// Sample is a recorded value of a metric
+type Sample struct {
+	At    time.Time
+	Value float64
+}
+
+// Backtest replays a recorded metric through a task's target, once acting on the metric as it happens and once
+// acting on the forecast, so we can see whether forecasting would have helped. New containers take StartupDelay
+// to start, and we apply the task's cooldowns and stabilization window. We compare both against a reference run
+// where containers start instantly and scale whenever the target says so.
+type Backtest struct {
+	Forecast      Config
+	StartupDelay  time.Duration
+	MinContainers int
+	MaxContainers int
+
+	ScaleUpCooldown     time.Duration
+	ScaleDownCooldown   time.Duration
+	StabilizationWindow time.Duration
+
+	// NewTarget creates a fresh copy of the task's target for each run, as targets have state
+	NewTarget func() target.Target
+}
+
+// Outcome is how one run behaved
+type Outcome struct {
+	ScaleOps          int     // number of times we changed the number of containers
+	MeanContainers    float64 // average containers running
+	UnderProvisioned  int     // total containers short of the reference, summed over all samples
+	OverProvisioned   int     // total containers more than the reference, summed over all samples
+	SamplesUnderScale int     // samples where we had fewer containers than the reference
+}
+
+// BacktestResult compares reactive and predictive scaling
+type BacktestResult struct {
+	Samples       int
+	Reactive      Outcome
+	Predictive    Outcome
+	ForecastError float64 // mean absolute difference between the forecast and what actually happened
+}
+
+// run is one simulated task
+type run struct {
+	target   target.Target
+	running  int
+	starting []time.Time // when each container that's starting will be running
+	total    int
+	outcome  Outcome
+
+	// Cooldowns and stabilization window only apply if the run is paced
+	paced      bool
+	lastScaled time.Time
+	ideals     []idealAt
+}
+
+// idealAt is the ideal number of containers at a particular time
+type idealAt struct {
+	at    time.Time
+	ideal int
+}
+
+// Run replays the samples, which should be in time order
+func (b *Backtest) Run(samples []Sample) (result BacktestResult, err error) {
+	hw, err := New(b.Forecast)
+	if err != nil {
+		return result, err
+	}
+
+	reference := b.newRun(false)
+	reactive := b.newRun(true)
+	predictive := b.newRun(true)
+
+	type prediction struct {
+		at    time.Time
+		value float64
+	}
+	var predictions []prediction
+	var errTotal float64
+	var errCount int
+
+	for _, s := range samples {
+		// Check the forecasts we made for this time
+		for len(predictions) > 0 && !predictions[0].at.After(s.At) {
+			errTotal += math.Abs(predictions[0].value - s.Value)
+			errCount++
+			predictions = predictions[1:]
+		}
+
+		hw.Add(s.At, s.Value)
+		forecast := s.Value
+		if hw.Ready() {
+			forecast = math.Max(0, hw.Forecast())
+			predictions = append(predictions, prediction{at: s.At.Add(hw.Horizon()), value: forecast})
+		}
+
+		reference.step(s.At, int(s.Value+0.5), 0, b)
+		reactive.step(s.At, int(s.Value+0.5), b.StartupDelay, b)
+		predictive.step(s.At, int(forecast+0.5), b.StartupDelay, b)
+
+		reactive.compare(reference)
+		predictive.compare(reference)
+	}
+
+	result.Samples = len(samples)
+	result.Reactive = reactive.finish(len(samples))
+	result.Predictive = predictive.finish(len(samples))
+	if errCount > 0 {
+		result.ForecastError = errTotal / float64(errCount)
+	}
+
+	return result, nil
+}
+
+func (b *Backtest) newRun(paced bool) *run {
+	return &run{
+		target:  b.NewTarget(),
+		running: b.MinContainers,
+		paced:   paced,
+	}
+}
+
+// step makes a scaling decision for a single task in the same way as the local engine. We don't scale while
+// containers are still starting, just as the engine waits for a scale operation to complete. The recorded
+// metric is just a value, so we don't have a dequeue rate or a limit on containers from the metric, and
+// there are no other tasks competing for capacity.
+func (r *run) step(now time.Time, value int, startupDelay time.Duration, b *Backtest) {
+	for len(r.starting) > 0 && !r.starting[0].After(now) {
+		r.running++
+		r.starting = r.starting[1:]
+	}
+
+	if len(r.starting) == 0 {
+		if o, ok := r.target.(target.Observer); ok {
+			o.Observe(r.running)
+		}
+
+		if tm, ok := r.target.(target.Timed); ok {
+			tm.At(now)
+		}
+
+		delta := r.stabilize(r.running+r.target.Delta(value), now, b) - r.running
+		if delta > 0 && r.target.Meeting(value) || delta < 0 && !r.target.Exceeding(value) {
+			delta = 0
+		}
+
+		if r.paced && (delta > 0 && now.Before(r.lastScaled.Add(b.ScaleUpCooldown)) ||
+			delta < 0 && now.Before(r.lastScaled.Add(b.ScaleDownCooldown))) {
+			delta = 0
+		}
+
+		if r.running+delta > b.MaxContainers {
+			delta = b.MaxContainers - r.running
+		}
+		if r.running+delta < b.MinContainers {
+			delta = b.MinContainers - r.running
+		}
+
+		if delta != 0 {
+			r.outcome.ScaleOps++
+			r.lastScaled = now
+		}
+
+		if delta < 0 || startupDelay == 0 {
+			r.running += delta
+		} else {
+			for i := 0; i < delta; i++ {
+				r.starting = append(r.starting, now.Add(startupDelay))
+			}
+		}
+	}
+
+	r.total += r.running
+}
+
+// stabilize only scales down as far as the highest ideal during the stabilization window, like demand.Task
+func (r *run) stabilize(ideal int, now time.Time, b *Backtest) int {
+	if !r.paced || b.StabilizationWindow <= 0 {
+		return ideal
+	}
+
+	cutoff := now.Add(-b.StabilizationWindow)
+	keep := r.ideals[:0]
+	for _, i := range r.ideals {
+		if i.at.After(cutoff) {
+			keep = append(keep, i)
+		}
+	}
+	r.ideals = append(keep, idealAt{at: now, ideal: ideal})
+
+	if ideal >= r.running {
+		return ideal
+	}
+
+	stabilized := ideal
+	for _, i := range r.ideals {
+		if i.ideal > stabilized {
+			stabilized = i.ideal
+		}
+	}
+
+	if stabilized > r.running {
+		stabilized = r.running
+	}
+	return stabilized
+}
+
+func (r *run) compare(reference *run) {
+	diff := r.running - reference.running
+	switch {
+	case diff < 0:
+		r.outcome.UnderProvisioned -= diff
+		r.outcome.SamplesUnderScale++
+	case diff > 0:
+		r.outcome.OverProvisioned += diff
+	}
+}
+
+func (r *run) finish(samples int) Outcome {
+	if samples > 0 {
+		r.outcome.MeanContainers = float64(r.total) / float64(samples)
+	}
+	return r.outcome
+}
+
+// ReadSamples reads a recorded metric with one sample per line. A line can be just the value, in which case
+// samples are interval apart, or a time and value separated by a comma. The time can be RFC 3339 or Unix
+// seconds. Blank lines and lines starting with # are ignored.
+func ReadSamples(r io.Reader, interval time.Duration) (samples []Sample, err error) {
+	scanner := bufio.NewScanner(r)
+	next := time.Unix(0, 0)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, ",")
+		s := Sample{At: next}
+
+		if len(fields) > 1 {
+			s.At, err = parseTime(strings.TrimSpace(fields[0]))
+			if err != nil {
+				return nil, fmt.Errorf("Bad time on line %d: %v", line, err)
+			}
+		}
+
+		s.Value, err = strconv.ParseFloat(strings.TrimSpace(fields[len(fields)-1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("Bad value on line %d: %v", line, err)
+		}
+
+		samples = append(samples, s)
+		next = s.At.Add(interval)
+	}
+
+	return samples, scanner.Err()
+}
+
+func parseTime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(secs*float64(time.Second))), nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,133 @@
+package forecast
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microscaling/microscaling/target"
+)
+
+func TestBacktest(t *testing.T) {
+	// Messages arrive faster and faster, then settle down
+	var samples []Sample
+	start := time.Now()
+	for i := 0; i < 600; i++ {
+		value := float64(i * 5)
+		if i > 300 {
+			value = 1500
+		}
+		samples = append(samples, Sample{At: start.Add(time.Duration(i) * 500 * time.Millisecond), Value: value})
+	}
+
+	b := Backtest{
+		Forecast:      Config{Horizon: "10s"},
+		StartupDelay:  10 * time.Second,
+		MinContainers: 1,
+		MaxContainers: 20,
+		NewTarget:     func() target.Target { return target.NewUtilizationTarget(100) },
+	}
+
+	result, err := b.Run(samples)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if result.Samples != 600 {
+		t.Fatalf("Expected 600 samples but got %d", result.Samples)
+	}
+
+	if result.Reactive.ScaleOps == 0 || result.Predictive.ScaleOps == 0 {
+		t.Fatalf("Expected both runs to scale: %v", result)
+	}
+
+	if result.Predictive.UnderProvisioned >= result.Reactive.UnderProvisioned {
+		t.Fatalf("Expected predictive scaling to be short of containers less often: %v", result)
+	}
+
+	if result.ForecastError <= 0 {
+		t.Fatalf("Expected a forecast error: %v", result)
+	}
+
+	b.Forecast.Horizon = ""
+	if _, err = b.Run(samples); err == nil {
+		t.Fatalf("Expected an error with a bad forecast config")
+	}
+}
+
+func TestBacktestCooldowns(t *testing.T) {
+	// Work comes and goes every 5 seconds
+	var samples []Sample
+	start := time.Now()
+	for i := 0; i < 240; i++ {
+		value := 1000.0
+		if (i/10)%2 == 1 {
+			value = 100
+		}
+		samples = append(samples, Sample{At: start.Add(time.Duration(i) * 500 * time.Millisecond), Value: value})
+	}
+
+	b := Backtest{
+		Forecast:      Config{Horizon: "10s"},
+		MinContainers: 1,
+		MaxContainers: 20,
+		NewTarget:     func() target.Target { return target.NewUtilizationTarget(100) },
+	}
+
+	unpaced, err := b.Run(samples)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Without a startup delay or cooldowns we scale just like the reference
+	if unpaced.Reactive.OverProvisioned != 0 || unpaced.Reactive.UnderProvisioned != 0 {
+		t.Fatalf("Expected to match the reference: %v", unpaced)
+	}
+
+	b.StabilizationWindow = 20 * time.Second
+	b.ScaleUpCooldown = 2 * time.Second
+	paced, err := b.Run(samples)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if paced.Reactive.ScaleOps >= unpaced.Reactive.ScaleOps {
+		t.Fatalf("Expected fewer scale ops with a stabilization window: %v vs %v", paced, unpaced)
+	}
+
+	if paced.Reactive.OverProvisioned == 0 {
+		t.Fatalf("Expected to hold on to containers during the dips: %v", paced)
+	}
+}
+
+func TestReadSamples(t *testing.T) {
+	input := `
+# queue length
+10
+20
+
+1483315200,30
+2017-01-02T00:00:01Z, 40
+`
+	samples, err := ReadSamples(strings.NewReader(input), 500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(samples) != 4 {
+		t.Fatalf("Expected 4 samples but got %d", len(samples))
+	}
+
+	if samples[1].At.Sub(samples[0].At) != 500*time.Millisecond || samples[1].Value != 20 {
+		t.Fatalf("Unexpected second sample %v", samples[1])
+	}
+
+	jan2 := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+	if !samples[2].At.Equal(jan2) || !samples[3].At.Equal(jan2.Add(time.Second)) || samples[3].Value != 40 {
+		t.Fatalf("Unexpected timed samples %v", samples[2:])
+	}
+
+	if _, err = ReadSamples(strings.NewReader("ten\n"), time.Second); err == nil {
+		t.Fatalf("Expected an error for a bad value")
+	}
+}
@@ -0,0 +1,213 @@
+// Package forecast predicts what a metric will be a little way ahead, so that we can start containers
+// before a queue builds up rather than after
+package forecast
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("mssforecast")
+
+// Config is the forecast settings for a task. Smoothing factors are between 0 and 1, and any that aren't set (nil)
+// use the defaults.
+type Config struct {
+	Horizon string   `json:"horizon"`         // how far ahead to predict, e.g. "30s"
+	Alpha   *float64 `json:"alpha,omitempty"` // how quickly the level follows the metric
+	Beta    *float64 `json:"beta,omitempty"`  // how quickly the trend follows changes in the level, 0 for no trend
+	Gamma   *float64 `json:"gamma,omitempty"` // how quickly the seasonal pattern changes, 0 to keep the first season's
+	Season  string   `json:"season"`          // length of a repeating pattern, e.g. "24h". No seasonality if not set.
+}
+
+const (
+	defaultAlpha float64 = 0.5
+	defaultBeta  float64 = 0.1
+	defaultGamma float64 = 0.1
+)
+
+// HoltWinters does triple exponential smoothing, following the level and trend of the metric and
+// optionally a seasonal pattern. Samples should arrive at a roughly regular interval, which we work
+// out from the first two.
+type HoltWinters struct {
+	config  Config
+	alpha   float64
+	beta    float64
+	gamma   float64
+	horizon time.Duration
+	season  time.Duration
+
+	samples  int
+	start    time.Time
+	last     time.Time
+	interval time.Duration
+	level    float64
+	trend    float64
+	seasonal []float64
+	seen     []bool // which parts of the first season we have samples for, until we've learnt the pattern
+}
+
+// New creates a forecaster from the config
+func New(c Config) (*HoltWinters, error) {
+	for _, f := range []*float64{c.Alpha, c.Beta, c.Gamma} {
+		if f != nil && (*f < 0 || *f > 1) {
+			return nil, fmt.Errorf("Smoothing factors must be between 0 and 1 but got %f", *f)
+		}
+	}
+
+	hw := &HoltWinters{
+		config: c,
+		alpha:  orDefault(c.Alpha, defaultAlpha),
+		beta:   orDefault(c.Beta, defaultBeta),
+		gamma:  orDefault(c.Gamma, defaultGamma),
+	}
+
+	var err error
+	hw.horizon, err = time.ParseDuration(c.Horizon)
+	if err != nil || hw.horizon <= 0 {
+		return nil, fmt.Errorf("Bad forecast horizon %q", c.Horizon)
+	}
+
+	if c.Season != "" {
+		hw.season, err = time.ParseDuration(c.Season)
+		if err != nil || hw.season <= 0 {
+			return nil, fmt.Errorf("Bad forecast season %q", c.Season)
+		}
+	}
+
+	return hw, nil
+}
+
+func orDefault(f *float64, def float64) float64 {
+	if f == nil {
+		return def
+	}
+	return *f
+}
+
+// Config returns the settings this forecaster was created with
+func (hw *HoltWinters) Config() Config {
+	return hw.config
+}
+
+// Horizon is how far ahead we predict
+func (hw *HoltWinters) Horizon() time.Duration {
+	return hw.horizon
+}
+
+// Add takes a new sample of the metric
+func (hw *HoltWinters) Add(at time.Time, value float64) {
+	switch hw.samples {
+	case 0:
+		hw.start = at
+		hw.level = value
+	case 1:
+		hw.interval = at.Sub(hw.start)
+		if hw.interval <= 0 {
+			// We can't learn anything from a sample at the same time
+			return
+		}
+
+		if hw.season > 0 {
+			hw.seasonal = make([]float64, int(hw.season/hw.interval)+1)
+			hw.seen = make([]bool, len(hw.seasonal))
+			hw.seasonal[0], hw.seen[0] = hw.level, true
+		}
+
+		hw.trend = value - hw.level
+		hw.level = value
+	default:
+		if !at.After(hw.last) {
+			return
+		}
+
+		if hw.seen != nil && at.Sub(hw.start) >= hw.season {
+			hw.learnSeason()
+		}
+
+		// Missed samples still move the trend on
+		steps := float64(at.Sub(hw.last)) / float64(hw.interval)
+		s := hw.seasonalAt(at)
+		level := hw.alpha*(value-s) + (1-hw.alpha)*(hw.level+steps*hw.trend)
+		hw.trend = hw.beta*(level-hw.level)/steps + (1-hw.beta)*hw.trend
+		hw.level = level
+	}
+
+	if hw.seasonal != nil {
+		i := hw.seasonIndex(at)
+		if hw.seen != nil {
+			// Keep the first season as it is until we've seen all of it
+			hw.seasonal[i], hw.seen[i] = value, true
+		} else {
+			hw.seasonal[i] = hw.gamma*(value-hw.level) + (1-hw.gamma)*hw.seasonal[i]
+		}
+	}
+
+	hw.samples++
+	hw.last = at
+}
+
+// Ready returns true once we have enough samples to forecast. With a season we need to have seen it once.
+func (hw *HoltWinters) Ready() bool {
+	if hw.samples < 2 {
+		return false
+	}
+
+	return hw.season == 0 || hw.last.Sub(hw.start) >= hw.season
+}
+
+// Forecast predicts the metric the horizon ahead of the last sample
+func (hw *HoltWinters) Forecast() float64 {
+	if hw.samples == 0 {
+		return 0
+	}
+
+	if hw.samples == 1 || hw.interval <= 0 {
+		return hw.level
+	}
+
+	at := hw.last.Add(hw.horizon)
+	steps := float64(hw.horizon) / float64(hw.interval)
+	f := hw.level + steps*hw.trend + hw.seasonalAt(at)
+
+	log.Debugf("[forecast] level %f trend %f -> %f in %v", hw.level, hw.trend, f, hw.horizon)
+	return f
+}
+
+func (hw *HoltWinters) seasonIndex(at time.Time) int {
+	offset := at.Sub(hw.start) % hw.season
+	return int(math.Floor(float64(offset)/float64(hw.interval))) % len(hw.seasonal)
+}
+
+func (hw *HoltWinters) seasonalAt(at time.Time) float64 {
+	if hw.seasonal == nil || hw.seen != nil {
+		return 0
+	}
+	return hw.seasonal[hw.seasonIndex(at)]
+}
+
+// learnSeason works out the seasonal pattern from how far each sample in the first season was from their
+// average. gamma only smooths changes to it after that, so a gamma of 0 keeps it fixed.
+func (hw *HoltWinters) learnSeason() {
+	var total float64
+	var n int
+	for i, seen := range hw.seen {
+		if seen {
+			total += hw.seasonal[i]
+			n++
+		}
+	}
+
+	mean := total / float64(n)
+	for i, seen := range hw.seen {
+		if seen {
+			hw.seasonal[i] -= mean
+		} else {
+			hw.seasonal[i] = 0
+		}
+	}
+
+	hw.seen = nil
+}
@@ -0,0 +1,124 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestNew(t *testing.T) {
+	bad := []Config{
+		{},
+		{Horizon: "soon"},
+		{Horizon: "-5s"},
+		{Horizon: "5s", Alpha: floatPtr(1.5)},
+		{Horizon: "5s", Gamma: floatPtr(-0.1)},
+		{Horizon: "5s", Season: "daily"},
+	}
+
+	for _, c := range bad {
+		if _, err := New(c); err == nil {
+			t.Errorf("Expected an error for %v", c)
+		}
+	}
+
+	hw, err := New(Config{Horizon: "30s"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if hw.alpha != defaultAlpha || hw.beta != defaultBeta || hw.gamma != defaultGamma {
+		t.Fatalf("Expected default smoothing factors")
+	}
+
+	// No trend and a fixed seasonal pattern are fine
+	hw, err = New(Config{Horizon: "30s", Beta: floatPtr(0), Gamma: floatPtr(0)})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if hw.alpha != defaultAlpha || hw.beta != 0 || hw.gamma != 0 {
+		t.Fatalf("Expected beta and gamma of 0 but got %f and %f", hw.beta, hw.gamma)
+	}
+}
+
+func TestForecastTrend(t *testing.T) {
+	hw, _ := New(Config{Horizon: "10s"})
+	start := time.Now()
+
+	if hw.Ready() || hw.Forecast() != 0 {
+		t.Fatalf("Shouldn't be ready without any samples")
+	}
+
+	// A steady rise of 2 a second
+	for i := 0; i < 100; i++ {
+		hw.Add(start.Add(time.Duration(i)*500*time.Millisecond), float64(i))
+	}
+
+	if !hw.Ready() {
+		t.Fatalf("Should be ready")
+	}
+
+	// The last sample is 99, and we expect 20 more in 10 seconds
+	if f := hw.Forecast(); math.Abs(f-119) > 0.5 {
+		t.Fatalf("Expected a forecast of 119 but got %f", f)
+	}
+}
+
+func TestForecastMissedSamples(t *testing.T) {
+	hw, _ := New(Config{Horizon: "1s", Alpha: floatPtr(1), Beta: floatPtr(1)})
+	start := time.Now()
+
+	hw.Add(start, 0)
+	hw.Add(start.Add(time.Second), 10)
+	hw.Add(start.Add(3*time.Second), 30) // missed a sample
+	hw.Add(start.Add(3*time.Second), 50) // same time, ignored
+
+	if f := hw.Forecast(); math.Abs(f-40) > 0.01 {
+		t.Fatalf("Expected a forecast of 40 but got %f", f)
+	}
+}
+
+func TestForecastSeasonal(t *testing.T) {
+	// A gamma of 0 keeps the pattern from the first season
+	for _, gamma := range []float64{0.5, 0} {
+		forecastSeasonal(t, gamma)
+	}
+}
+
+func forecastSeasonal(t *testing.T, gamma float64) {
+	hw, _ := New(Config{Horizon: "10s", Alpha: floatPtr(0.2), Beta: floatPtr(0.01), Gamma: floatPtr(gamma), Season: "1m"})
+	start := time.Now()
+
+	// Busy for the first 20 seconds of every minute
+	value := func(at time.Time) float64 {
+		if at.Sub(start)%time.Minute < 20*time.Second {
+			return 100
+		}
+		return 10
+	}
+
+	var at time.Time
+	for i := 0; i < 20*60; i++ {
+		at = start.Add(time.Duration(i) * time.Second)
+		hw.Add(at, value(at))
+
+		if i == 30 && hw.Ready() {
+			t.Fatalf("Shouldn't be ready before we've seen a whole season")
+		}
+	}
+
+	// 10 seconds before the busy period starts, we should see it coming
+	for at.Add(10*time.Second).Sub(start)%time.Minute != 0 {
+		at = at.Add(time.Second)
+		hw.Add(at, value(at))
+	}
+
+	if f := hw.Forecast(); f < 80 {
+		t.Fatalf("Gamma %f: expected a forecast of around 100 but got %f", gamma, f)
+	}
+}
@@ -31,13 +31,17 @@ import (
 	"github.com/op/go-logging"
 	"golang.org/x/net/websocket"
 
+	"github.com/microscaling/microscaling/config"
 	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/monitor"
 	"github.com/microscaling/microscaling/scheduler"
+	"github.com/microscaling/microscaling/target"
 	"github.com/microscaling/microscaling/utils"
 )
 
 const constGetMetricsTimeout = 500  // milliseconds - read state from the scheduler this often
 const constSendMetricsTimeout = 500 // milliseconds - send on the metrics API this often
+const constConfigPollTimeout = 2000 // milliseconds - check whether the config has changed this often
 
 var (
 	log = logging.MustGetLogger("mssagent")
@@ -62,6 +66,105 @@ func cleanup(s scheduler.Scheduler, tasks *demand.Tasks) {
 	}
 }
 
+// reloadTasks gets the latest config and reconciles it with the tasks we're already managing. If anything
+// goes wrong we carry on with the tasks we already have.
+func reloadTasks(c config.Config, st settings, s scheduler.Scheduler, tasks *demand.Tasks, demandUpdate chan struct{}) {
+	var added demand.Tasks
+	var started []*demand.Task
+
+	configured, maxContainers, err := c.GetApps(st.userID)
+	if err != nil {
+		log.Errorf("Failed to reload tasks: %v", err)
+		return
+	}
+
+	setTaskEnv(configured)
+
+	// Let the scheduler know about new task types, and check if there are already any of them running,
+	// before the demand engine can start scaling them. If a new task fails we leave it out, but still
+	// apply the rest of the changes.
+	for _, task := range configured {
+		tasks.RLock()
+		_, err := tasks.GetTask(task.Name)
+		tasks.RUnlock()
+
+		if err != nil {
+			err = s.InitScheduler(task)
+			if err != nil {
+				log.Errorf("Failed to start task %s: %v", task.Name, err)
+				continue
+			}
+
+			added.Tasks = append(added.Tasks, task)
+		}
+
+		started = append(started, task)
+	}
+
+	if len(added.Tasks) > 0 {
+		err = s.CountAllTasks(&added)
+		if err != nil {
+			log.Errorf("Failed to count containers. %v", err)
+		}
+
+		for _, task := range added.Tasks {
+			task.Requested = task.Running
+		}
+	}
+
+	// Nodes or hosts may have been added or removed since we last looked
+	if st.discoverMax {
+		maxResources, err := getMaxResources(st, s)
+		if err != nil {
+			log.Errorf("Failed to discover max resources, keeping the previous totals: %v", err)
+		} else {
+			tasks.Lock()
+			tasks.MaxResources = maxResources
+			tasks.Unlock()
+		}
+	}
+
+	if tasks.Reconcile(started, maxContainers) {
+		// Demand is now 0 for tasks that have been removed, so get the scheduler to scale them down
+		demandUpdate <- struct{}{}
+	}
+}
+
+// requestedCounts returns the number of containers requested for each task, so we can see what the scheduler changes
+func requestedCounts(tasks *demand.Tasks) map[string]int {
+	tasks.RLock()
+	defer tasks.RUnlock()
+
+	requested := make(map[string]int, len(tasks.Tasks))
+	for _, task := range tasks.Tasks {
+		requested[task.Name] = task.Requested
+	}
+
+	return requested
+}
+
+// recordScaling tells any monitors that record scaling operations what the scheduler just did
+func recordScaling(monitors []monitor.Monitor, tasks *demand.Tasks, requested map[string]int, err error) {
+	for _, m := range monitors {
+		r, ok := m.(monitor.Recorder)
+		if !ok {
+			continue
+		}
+
+		if err != nil {
+			r.SchedulerError(err)
+		}
+
+		tasks.RLock()
+		for _, task := range tasks.Tasks {
+			if from, ok := requested[task.Name]; ok && from != task.Requested {
+				r.ScaledTask(task.Name, from, task.Requested)
+			}
+		}
+		tasks.RUnlock()
+	}
+}
+
 // For this simple prototype, Microscaling sits in a loop checking for demand changes every X milliseconds
 func main() {
 	var err error
@@ -69,6 +172,14 @@ func main() {
 
 	st := getSettings()
 
+	if st.backtestFile != "" {
+		err = runBacktest(st, os.Stdout)
+		if err != nil {
+			log.Errorf("Backtest failed: %v", err)
+		}
+		return
+	}
+
 	// Sending an empty struct on this channel triggers the scheduler to make updates
 	demandUpdate := make(chan struct{}, 1)
 
@@ -78,12 +189,26 @@ func main() {
 		return
 	}
 
-	tasks, err = getTasks(st)
+	c, err := getConfig(st)
+	if err != nil {
+		log.Errorf("Failed to get config: %v", err)
+		return
+	}
+
+	target.SetTuningStore(target.NewFileTuningStore(st.pidTuningFile))
+
+	tasks, err = loadTasks(c, st)
 	if err != nil {
 		log.Errorf("Failed to get tasks: %v", err)
 		return
 	}
 
+	tasks.MaxResources, err = getMaxResources(st, s)
+	if err != nil {
+		log.Errorf("Failed to get max resources: %v", err)
+		return
+	}
+
 	// Let the scheduler know about the task types.
 	for _, task := range tasks.Tasks {
 		err = s.InitScheduler(task)
@@ -110,6 +235,21 @@ func main() {
 	signal.Notify(closedown, os.Interrupt)
 	signal.Notify(closedown, syscall.SIGTERM)
 
+	// Reload config when we receive a hangup, periodically if configured, or when the config tells us it has changed
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	var configRefresh <-chan time.Time
+	if st.configRefresh > 0 {
+		configRefresh = time.NewTicker(st.configRefresh).C
+	}
+
+	var configChanged <-chan time.Time
+	changer, canChange := c.(config.Changer)
+	if canChange {
+		configChanged = time.NewTicker(constConfigPollTimeout * time.Millisecond).C
+	}
+
 	var ws *websocket.Conn
 
 	// Open a web socket to the server if needed.
@@ -127,15 +267,23 @@ func main() {
 		return
 	}
 
+	monitors, err := getMonitors(st, ws)
+	if err != nil {
+		log.Errorf("Failed to get monitors: %v", err)
+		return
+	}
+
 	go de.GetDemand(tasks, demandUpdate)
 
 	// Handle demand updates
 	go func() {
 		for range demandUpdate {
+			requested := requestedCounts(tasks)
 			err = s.StopStartTasks(tasks)
 			if err != nil {
 				log.Errorf("Failed to stop / start tasks. %v", err)
 			}
+			recordScaling(monitors, tasks, requested, err)
 		}
 
 		// When the demandUpdate channel is closed, it's time to scale everything down to 0
@@ -151,11 +299,13 @@ func main() {
 			if err != nil {
 				log.Errorf("Failed to count containers. %v", err)
 			}
+
+			// Stop managing any tasks that were removed from the config once they have scaled down to 0
+			tasks.RemoveDrained()
 		}
 	}()
 
 	// Periodically send metrics to any monitors
-	monitors := getMonitors(st, ws)
 	if len(monitors) > 0 {
 		sendMetricsTimeout := time.NewTicker(constSendMetricsTimeout * time.Millisecond)
 		go func() {
@@ -170,8 +320,28 @@ func main() {
 		}()
 	}
 
+	// Reload config until we're asked to close down. We do this here rather than in another goroutine so that
+	// a reload can't race with the demand engine closing the demandUpdate channel.
+waiting:
+	for {
+		select {
+		case <-reload:
+			log.Info("Reloading config on hangup")
+			reloadTasks(c, st, s, tasks, demandUpdate)
+		case <-configRefresh:
+			log.Debug("Refreshing config")
+			reloadTasks(c, st, s, tasks, demandUpdate)
+		case <-configChanged:
+			if changer.Changed() {
+				log.Info("Reloading changed config")
+				reloadTasks(c, st, s, tasks, demandUpdate)
+			}
+		case <-closedown:
+			break waiting
+		}
+	}
+
 	// When we're asked to close down, we don't want to handle demand updates any more
-	<-closedown
 	log.Info("Clean up when ready")
 	// Give the scheduler a chance to do any necessary cleanup
 	s.Cleanup()
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/scheduler/toy"
+)
+
+// fixedConfig always returns the same tasks
+type fixedConfig struct {
+	tasks []*demand.Task
+}
+
+func (f *fixedConfig) GetApps(userID string) ([]*demand.Task, int, error) {
+	return f.tasks, 10, nil
+}
+
+// pickyScheduler can't start one of the tasks
+type pickyScheduler struct {
+	*toy.ToyScheduler
+	broken string
+}
+
+func (p *pickyScheduler) InitScheduler(task *demand.Task) error {
+	if task.Name == p.broken {
+		return fmt.Errorf("Can't start %s", task.Name)
+	}
+	return p.ToyScheduler.InitScheduler(task)
+}
+
+// discoveringScheduler can tell us how much CPU and memory there is
+type discoveringScheduler struct {
+	*toy.ToyScheduler
+	capacity demand.Resources
+}
+
+func (d *discoveringScheduler) DiscoverCapacity() (demand.Resources, error) {
+	return d.capacity, nil
+}
+
+func TestReloadTasksDiscoversResources(t *testing.T) {
+	tasks := &demand.Tasks{MaxResources: demand.Resources{CPU: 2000, Memory: 4096}}
+	c := &fixedConfig{}
+	s := &discoveringScheduler{ToyScheduler: toy.NewScheduler(), capacity: demand.Resources{CPU: 4000, Memory: 8192}}
+
+	// A node was added since we started
+	reloadTasks(c, settings{discoverMax: true}, s, tasks, make(chan struct{}, 1))
+	if tasks.MaxResources != s.capacity {
+		t.Fatalf("Expected to discover %+v but have %+v", s.capacity, tasks.MaxResources)
+	}
+}
+
+func TestReloadTasksSkipsFailedTask(t *testing.T) {
+	tasks := &demand.Tasks{Tasks: []*demand.Task{
+		{Name: "web", MaxContainers: 5},
+		{Name: "old", Running: 2, Requested: 2},
+	}}
+
+	c := &fixedConfig{tasks: []*demand.Task{
+		{Name: "broken"},
+		{Name: "web", MaxContainers: 8},
+		{Name: "worker"},
+	}}
+
+	s := &pickyScheduler{ToyScheduler: toy.NewScheduler(), broken: "broken"}
+	demandUpdate := make(chan struct{}, 1)
+
+	reloadTasks(c, settings{}, s, tasks, demandUpdate)
+
+	if _, err := tasks.GetTask("broken"); err == nil {
+		t.Errorf("Task that failed to start shouldn't have been added")
+	}
+
+	if _, err := tasks.GetTask("worker"); err != nil {
+		t.Errorf("New task should have been added")
+	}
+
+	web, err := tasks.GetTask("web")
+	if err != nil || web.MaxContainers != 8 {
+		t.Errorf("Existing task should have been updated")
+	}
+
+	old, err := tasks.GetTask("old")
+	if err != nil || !old.Draining {
+		t.Errorf("Removed task should be draining")
+	}
+
+	select {
+	case <-demandUpdate:
+	default:
+		t.Errorf("Expected a demand update to scale down the removed task")
+	}
+}
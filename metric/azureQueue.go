@@ -3,6 +3,7 @@ package metric
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/storage"
 )
@@ -18,6 +19,7 @@ var azureInitialized = false
 // AzureQueueMetric is used to measure the length of an Azure Storage Accout Queue
 type AzureQueueMetric struct {
 	currentVal     int
+	updated        time.Time
 	azureQueueName string
 }
 
@@ -57,16 +59,23 @@ func NewAzureQueueMetric(queueName string) *AzureQueueMetric {
 }
 
 // UpdateCurrent calls the Azure Storage API to get the queue length and stores the value in the metric.
-func (aqm *AzureQueueMetric) UpdateCurrent() {
+func (aqm *AzureQueueMetric) UpdateCurrent() error {
 	metadata, err := azureQueueClient.GetMetadata(aqm.azureQueueName)
 	if err != nil {
-		log.Errorf("Error getting Azure queue info: %v", err)
+		return fmt.Errorf("Error getting Azure queue info: %v", err)
 	}
 	aqm.currentVal = metadata.ApproximateMessageCount
+	aqm.updated = time.Now()
 	log.Debugf("Queue name %s length %d", aqm.azureQueueName, aqm.currentVal)
+	return nil
 }
 
 // Current reads out the value of the current queue length
 func (aqm *AzureQueueMetric) Current() int {
 	return aqm.currentVal
 }
+
+// Updated returns when we last got the queue length successfully
+func (aqm *AzureQueueMetric) Updated() time.Time {
+	return aqm.updated
+}
@@ -0,0 +1,135 @@
+package metric
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+)
+
+// SQS only reports how many messages have been deleted through CloudWatch, which isn't part of the AWS SDK
+// we vendor, so this is a minimal client for the one call we need.
+
+// deletedCounter tells us how many messages were deleted from an SQS queue in the most recent period
+// that CloudWatch has published, and when that period started. ok is false if there's no data yet.
+type deletedCounter interface {
+	MessagesDeleted(queueName string, now time.Time) (count float64, start time.Time, ok bool, err error)
+}
+
+const cloudWatchServiceName string = "monitoring"
+
+// SQS publishes its metrics every minute, and they can take a few minutes to appear
+const sqsMetricPeriod = time.Minute
+const sqsMetricLookback = 10 * time.Minute
+
+type cloudWatchClient struct {
+	*client.Client
+}
+
+// compile-time assert that we implement the right interface
+var _ deletedCounter = (*cloudWatchClient)(nil)
+
+func newCloudWatchClient(p client.ConfigProvider, cfgs ...*aws.Config) *cloudWatchClient {
+	c := p.ClientConfig(cloudWatchServiceName, cfgs...)
+	cw := &cloudWatchClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   cloudWatchServiceName,
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2010-08-01",
+			},
+			c.Handlers,
+		),
+	}
+
+	cw.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	cw.Handlers.Build.PushBackNamed(query.BuildHandler)
+	cw.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	cw.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	cw.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+	return cw
+}
+
+type cloudWatchDimension struct {
+	_ struct{} `type:"structure"`
+
+	Name  *string `type:"string"`
+	Value *string `type:"string"`
+}
+
+type getMetricStatisticsInput struct {
+	_ struct{} `type:"structure"`
+
+	Namespace  *string                `type:"string"`
+	MetricName *string                `type:"string"`
+	Dimensions []*cloudWatchDimension `type:"list"`
+	StartTime  *time.Time             `type:"timestamp" timestampFormat:"iso8601"`
+	EndTime    *time.Time             `type:"timestamp" timestampFormat:"iso8601"`
+	Period     *int64                 `type:"integer"`
+	Statistics []*string              `type:"list"`
+}
+
+type cloudWatchDatapoint struct {
+	_ struct{} `type:"structure"`
+
+	Sum       *float64   `type:"double"`
+	Timestamp *time.Time `type:"timestamp" timestampFormat:"iso8601"`
+}
+
+type getMetricStatisticsOutput struct {
+	_ struct{} `type:"structure"`
+
+	Datapoints []*cloudWatchDatapoint `type:"list"`
+}
+
+// MessagesDeleted gets the NumberOfMessagesDeleted datapoints for the queue over the last few minutes, and
+// returns the latest one
+func (cw *cloudWatchClient) MessagesDeleted(queueName string, now time.Time) (count float64, start time.Time, ok bool, err error) {
+	op := &request.Operation{
+		Name:       "GetMetricStatistics",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+
+	input := &getMetricStatisticsInput{
+		Namespace:  aws.String("AWS/SQS"),
+		MetricName: aws.String("NumberOfMessagesDeleted"),
+		Dimensions: []*cloudWatchDimension{
+			{Name: aws.String("QueueName"), Value: aws.String(queueName)},
+		},
+		StartTime:  aws.Time(now.Add(-sqsMetricLookback)),
+		EndTime:    aws.Time(now),
+		Period:     aws.Int64(int64(sqsMetricPeriod / time.Second)),
+		Statistics: []*string{aws.String("Sum")},
+	}
+	output := &getMetricStatisticsOutput{}
+
+	err = cw.NewRequest(op, input, output).Send()
+	if err != nil {
+		return 0, start, false, err
+	}
+
+	var latest *cloudWatchDatapoint
+	for _, d := range output.Datapoints {
+		if d.Sum == nil || d.Timestamp == nil || d.Timestamp.Add(sqsMetricPeriod).After(now) {
+			// The latest period may not be complete yet
+			continue
+		}
+		if latest == nil || d.Timestamp.After(*latest.Timestamp) {
+			latest = d
+		}
+	}
+
+	if latest == nil {
+		return 0, start, false, nil
+	}
+
+	return *latest.Sum, *latest.Timestamp, true, nil
+}
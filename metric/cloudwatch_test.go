@@ -0,0 +1,67 @@
+package metric
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestCloudWatchMessagesDeleted(t *testing.T) {
+	now := time.Date(2017, 3, 1, 12, 0, 30, 0, time.UTC)
+
+	// Stand-in for CloudWatch. The datapoint for 12:00 isn't complete yet so we should use the one before.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		expected := map[string]string{
+			"Action":                    "GetMetricStatistics",
+			"Namespace":                 "AWS/SQS",
+			"MetricName":                "NumberOfMessagesDeleted",
+			"Dimensions.member.1.Name":  "QueueName",
+			"Dimensions.member.1.Value": "microscaling-test",
+			"Statistics.member.1":       "Sum",
+			"Period":                    "60",
+			"EndTime":                   "2017-03-01T12:00:30Z",
+		}
+		for k, v := range expected {
+			if r.Form.Get(k) != v {
+				t.Errorf("Expected %s to be %s but was %s", k, v, r.Form.Get(k))
+			}
+		}
+
+		w.Write([]byte(`<GetMetricStatisticsResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+  <GetMetricStatisticsResult>
+    <Datapoints>
+      <member><Timestamp>2017-03-01T11:58:00Z</Timestamp><Sum>300.0</Sum><Unit>Count</Unit></member>
+      <member><Timestamp>2017-03-01T12:00:00Z</Timestamp><Sum>10.0</Sum><Unit>Count</Unit></member>
+      <member><Timestamp>2017-03-01T11:59:00Z</Timestamp><Sum>600.0</Sum><Unit>Count</Unit></member>
+    </Datapoints>
+    <Label>NumberOfMessagesDeleted</Label>
+  </GetMetricStatisticsResult>
+</GetMetricStatisticsResponse>`))
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	cw := newCloudWatchClient(sess)
+	count, start, ok, err := cw.MessagesDeleted("microscaling-test", now)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if !ok || count != 600 || !start.Equal(time.Date(2017, 3, 1, 11, 59, 0, 0, time.UTC)) {
+		t.Fatalf("Expected 600 messages in the minute from 11:59 but got %f from %v (%t)", count, start, ok)
+	}
+}
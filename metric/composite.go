@@ -0,0 +1,132 @@
+package metric
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ways of combining the values of the metrics in a composite
+const (
+	CompositeSum string = "sum"
+	CompositeMax string = "max"
+)
+
+// CompositeMetric combines several metrics, for tasks that get work from more than one place. Each value is
+// multiplied by its weight, and then we take either the sum or the max.
+type CompositeMetric struct {
+	metrics     []Metric
+	weights     []float64
+	aggregation string
+	currentVal  int
+}
+
+// compile-time assert that we implement the right interfaces
+var _ Metric = (*CompositeMetric)(nil)
+var _ ContainerLimiter = (*CompositeMetric)(nil)
+
+// NewCompositeMetric combines the metrics. Weights default to 1 if there aren't enough of them, and the
+// aggregation defaults to sum. A weight of 0 leaves that metric out.
+func NewCompositeMetric(metrics []Metric, weights []float64, aggregation string) *CompositeMetric {
+	w := make([]float64, len(metrics))
+	for i := range w {
+		w[i] = 1
+		if i < len(weights) {
+			w[i] = weights[i]
+		}
+	}
+
+	if aggregation == "" {
+		aggregation = CompositeSum
+	}
+
+	return &CompositeMetric{
+		metrics:     metrics,
+		weights:     w,
+		aggregation: strings.ToLower(aggregation),
+	}
+}
+
+// UpdateCurrent reads all the metrics at the same time. If any of them fail we keep the last value, as
+// the combination wouldn't mean much without them all.
+func (c *CompositeMetric) UpdateCurrent() error {
+	var updating sync.WaitGroup
+	errs := make([]error, len(c.metrics))
+
+	for i, m := range c.metrics {
+		updating.Add(1)
+		go func(i int, m Metric) {
+			defer updating.Done()
+			errs[i] = m.UpdateCurrent()
+		}(i, m)
+	}
+
+	updating.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("metric %d: %v", i, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Failed to update composite metric: %s", strings.Join(failed, "; "))
+	}
+
+	var total float64
+	for i, m := range c.metrics {
+		v := c.weights[i] * float64(m.Current())
+		switch c.aggregation {
+		case CompositeMax:
+			if i == 0 || v > total {
+				total = v
+			}
+		default:
+			total += v
+		}
+	}
+
+	c.currentVal = int(total + 0.5)
+	log.Debugf("Composite metric %s of %d metrics: %d", c.aggregation, len(c.metrics), c.currentVal)
+	return nil
+}
+
+// Current returns the combined value
+func (c *CompositeMetric) Current() int {
+	return c.currentVal
+}
+
+// Updated is when the least recently updated metric was last read, as that's how out of date the combined value could be
+func (c *CompositeMetric) Updated() (updated time.Time) {
+	for i, m := range c.metrics {
+		if u := m.Updated(); i == 0 || u.Before(updated) {
+			updated = u
+		}
+	}
+
+	return updated
+}
+
+// MaxContainers is the largest limit of any of the metrics, since there's work for that many containers from
+// that metric. There's no limit unless all the metrics have one.
+func (c *CompositeMetric) MaxContainers() (max int) {
+	for _, m := range c.metrics {
+		l, ok := m.(ContainerLimiter)
+		if !ok {
+			return 0
+		}
+
+		limit := l.MaxContainers()
+		if limit == 0 {
+			return 0
+		}
+
+		if limit > max {
+			max = limit
+		}
+	}
+
+	return max
+}
@@ -0,0 +1,93 @@
+package metric
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// limitedMetric is a toy metric with a container limit
+type limitedMetric struct {
+	ToyMetric
+	limit int
+}
+
+func (l *limitedMetric) MaxContainers() int {
+	return l.limit
+}
+
+func TestCompositeMetric(t *testing.T) {
+	sqs := NewToyMetric()
+	nsq := NewToyMetric()
+	sqs.SettableCurrent = 30
+	nsq.SettableCurrent = 50
+
+	tests := []struct {
+		aggregation string
+		weights     []float64
+		expected    int
+	}{
+		{aggregation: "", expected: 80},
+		{aggregation: CompositeSum, weights: []float64{2}, expected: 110},
+		{aggregation: CompositeMax, expected: 50},
+		{aggregation: "MAX", weights: []float64{2, 0.5}, expected: 60},
+		{aggregation: CompositeSum, weights: []float64{0.5, 0.25}, expected: 28}, // 27.5 rounds up
+		{aggregation: CompositeSum, weights: []float64{0, 1}, expected: 50},      // muted
+	}
+
+	for _, tc := range tests {
+		c := NewCompositeMetric([]Metric{sqs, nsq}, tc.weights, tc.aggregation)
+		if err := c.UpdateCurrent(); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if c.Current() != tc.expected {
+			t.Errorf("%s %v: expected %d but got %d", tc.aggregation, tc.weights, tc.expected, c.Current())
+		}
+	}
+}
+
+func TestCompositeMetricErrors(t *testing.T) {
+	now := time.Now()
+	sqs := NewToyMetric()
+	nsq := NewToyMetric()
+	sqs.SettableCurrent = 30
+	sqs.SettableUpdated = now
+	nsq.SettableCurrent = 50
+	nsq.SettableUpdated = now.Add(-time.Minute)
+
+	c := NewCompositeMetric([]Metric{sqs, nsq}, nil, CompositeSum)
+	if err := c.UpdateCurrent(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if !c.Updated().Equal(nsq.SettableUpdated) {
+		t.Fatalf("Expected updated to be the oldest of the metrics")
+	}
+
+	// If one fails we keep the last value
+	nsq.SettableErr = errors.New("Connection refused")
+	sqs.SettableCurrent = 100
+	if err := c.UpdateCurrent(); err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if c.Current() != 80 {
+		t.Fatalf("Expected to keep the last value 80 but got %d", c.Current())
+	}
+}
+
+func TestCompositeMetricMaxContainers(t *testing.T) {
+	kafka := &limitedMetric{limit: 4}
+	other := &limitedMetric{limit: 6}
+
+	c := NewCompositeMetric([]Metric{kafka, other}, nil, "")
+	if c.MaxContainers() != 6 {
+		t.Fatalf("Expected the largest limit 6 but got %d", c.MaxContainers())
+	}
+
+	c = NewCompositeMetric([]Metric{kafka, NewToyMetric()}, nil, "")
+	if c.MaxContainers() != 0 {
+		t.Fatalf("Expected no limit but got %d", c.MaxContainers())
+	}
+}
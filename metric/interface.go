@@ -2,13 +2,31 @@
 package metric
 
 import (
+	"time"
+
 	"github.com/op/go-logging"
 )
 
 // Metric is something we measure. Each task is associated with a Metric and a Target that we want the Metric to stay close to.
 type Metric interface {
-	UpdateCurrent()
+	// UpdateCurrent reads the latest value. If that fails we keep the last value and return the error.
+	UpdateCurrent() error
 	Current() int
+	// Updated is when we last read the value successfully, so we can tell if it's out of date
+	Updated() time.Time
+}
+
+// ContainerLimiter is implemented by metrics that know the most containers it's useful to run for a task,
+// e.g. Kafka consumers beyond the number of partitions would have nothing to do. 0 means there's no limit.
+type ContainerLimiter interface {
+	MaxContainers() int
+}
+
+// RateReporter is implemented by queue metrics that can tell how quickly messages are being taken off the queue,
+// in messages per second. since is when the period the rate was measured over started, as some metrics lag
+// behind the queue length. ok is false until we've been able to measure it.
+type RateReporter interface {
+	DequeueRate() (rate float64, since time.Time, ok bool)
 }
 
 var log = logging.MustGetLogger("mssmetric")
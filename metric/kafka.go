@@ -0,0 +1,174 @@
+package metric
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/microscaling/microscaling/utils"
+)
+
+const constBurrowEndpoint string = "http://127.0.0.1:8000"
+
+// Ways of combining the lag for each partition
+const (
+	KafkaLagSum = "sum"
+	KafkaLagMax = "max"
+)
+
+// compile-time assert that we implement the right interfaces
+var _ Metric = (*KafkaLagMetric)(nil)
+var _ ContainerLimiter = (*KafkaLagMetric)(nil)
+
+// KafkaLagMetric measures how far a Kafka consumer group is behind on a topic, using the Burrow
+// (https://github.com/linkedin/Burrow) HTTP API. We can use the total lag across all partitions, or the
+// lag of the partition that's furthest behind. Each partition is only read by one consumer in the group,
+// so there's no point running more consumers than there are partitions.
+type KafkaLagMetric struct {
+	currentVal    int
+	updated       time.Time
+	partitions    int
+	endpoint      string
+	cluster       string
+	consumerGroup string
+	topicName     string
+	lagMode       string
+}
+
+// burrowLagResponse from the consumer lag API
+type burrowLagResponse struct {
+	Error   bool         `json:"error"`
+	Message string       `json:"message"`
+	Status  burrowStatus `json:"status"`
+}
+
+type burrowStatus struct {
+	Partitions []burrowPartition `json:"partitions"`
+}
+
+type burrowPartition struct {
+	Topic      string `json:"topic"`
+	Partition  int    `json:"partition"`
+	CurrentLag int    `json:"current_lag"`
+}
+
+// burrowTopicResponse from the topic API, which has the latest offset for each partition
+type burrowTopicResponse struct {
+	Error   bool    `json:"error"`
+	Message string  `json:"message"`
+	Offsets []int64 `json:"offsets"`
+}
+
+// NewKafkaLagMetric creates the metric. If endpoint is empty we use BURROW_ENDPOINT, or the default local address.
+func NewKafkaLagMetric(endpoint string, cluster string, consumerGroup string, topicName string, lagMode string) *KafkaLagMetric {
+	if endpoint == "" {
+		endpoint = getEnvOrDefault("BURROW_ENDPOINT", constBurrowEndpoint)
+	}
+
+	if lagMode == "" {
+		lagMode = KafkaLagSum
+	}
+
+	return &KafkaLagMetric{
+		endpoint:      endpoint,
+		cluster:       cluster,
+		consumerGroup: consumerGroup,
+		topicName:     topicName,
+		lagMode:       lagMode,
+	}
+}
+
+// UpdateCurrent gets the lag for the consumer group, and the number of partitions in the topic. If anything
+// goes wrong we keep the last values. We can carry on without an up to date partition count, so we only
+// report an error if we can't get the lag.
+func (km *KafkaLagMetric) UpdateCurrent() error {
+	partitions, err := km.getPartitionCount()
+	if err != nil {
+		log.Errorf("Error getting partitions for Kafka topic %s: %v", km.topicName, err)
+	} else {
+		km.partitions = partitions
+	}
+
+	lag, err := km.getLag()
+	if err != nil {
+		return fmt.Errorf("Error getting Kafka lag for group %s: %v", km.consumerGroup, err)
+	}
+
+	km.currentVal = lag
+	km.updated = time.Now()
+	log.Debugf("Kafka group %s topic %s: %s lag %d, %d partitions", km.consumerGroup, km.topicName, km.lagMode, km.currentVal, km.partitions)
+	return nil
+}
+
+// Current returns the lag.
+func (km *KafkaLagMetric) Current() int {
+	return km.currentVal
+}
+
+// Updated returns when we last got the lag successfully.
+func (km *KafkaLagMetric) Updated() time.Time {
+	return km.updated
+}
+
+// MaxContainers returns the number of partitions, as any more consumers than that would be idle
+func (km *KafkaLagMetric) MaxContainers() int {
+	return km.partitions
+}
+
+func (km *KafkaLagMetric) getLag() (lag int, err error) {
+	var resp burrowLagResponse
+
+	u := km.endpoint + "/v3/kafka/" + url.PathEscape(km.cluster) + "/consumer/" + url.PathEscape(km.consumerGroup) + "/lag"
+	body, err := utils.GetJSON(u)
+	if err != nil {
+		return 0, err
+	}
+
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to decode response %s: %v", string(body), err)
+	}
+
+	if resp.Error {
+		return 0, fmt.Errorf("Burrow error: %s", resp.Message)
+	}
+
+	for _, p := range resp.Status.Partitions {
+		if p.Topic != km.topicName {
+			continue
+		}
+
+		switch km.lagMode {
+		case KafkaLagMax:
+			if p.CurrentLag > lag {
+				lag = p.CurrentLag
+			}
+		default:
+			lag += p.CurrentLag
+		}
+	}
+
+	return lag, nil
+}
+
+func (km *KafkaLagMetric) getPartitionCount() (partitions int, err error) {
+	var resp burrowTopicResponse
+
+	u := km.endpoint + "/v3/kafka/" + url.PathEscape(km.cluster) + "/topic/" + url.PathEscape(km.topicName)
+	body, err := utils.GetJSON(u)
+	if err != nil {
+		return 0, err
+	}
+
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to decode response %s: %v", string(body), err)
+	}
+
+	if resp.Error {
+		return 0, fmt.Errorf("Burrow error: %s", resp.Message)
+	}
+
+	return len(resp.Offsets), nil
+}
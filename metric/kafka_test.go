@@ -0,0 +1,65 @@
+package metric
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Stand-in for the parts of the Burrow API that we use
+func burrowServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/kafka/local/consumer/workers/lag":
+			w.Write([]byte(`{"error":false,"message":"consumer status returned","status":{"cluster":"local","group":"workers","status":"WARN","partitions":[
+				{"topic":"jobs","partition":0,"status":"OK","current_lag":5},
+				{"topic":"jobs","partition":1,"status":"WARN","current_lag":20},
+				{"topic":"jobs","partition":2,"status":"OK","current_lag":0},
+				{"topic":"other","partition":0,"status":"OK","current_lag":1000}
+			]}}`))
+		case "/v3/kafka/local/topic/jobs":
+			w.Write([]byte(`{"error":false,"message":"topic offsets returned","offsets":[100,200,300]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":true,"message":"cluster or consumer not found"}`))
+		}
+	}))
+}
+
+func TestKafkaLagMetric(t *testing.T) {
+	tests := []struct {
+		name          string
+		consumerGroup string
+		topicName     string
+		lagMode       string
+		expected      int
+		partitions    int
+	}{
+		{name: "sum", consumerGroup: "workers", topicName: "jobs", expected: 25, partitions: 3},
+		{name: "explicit sum", consumerGroup: "workers", topicName: "jobs", lagMode: KafkaLagSum, expected: 25, partitions: 3},
+		{name: "max", consumerGroup: "workers", topicName: "jobs", lagMode: KafkaLagMax, expected: 20, partitions: 3},
+		{name: "missing group", consumerGroup: "idle", topicName: "jobs", expected: 99, partitions: 3},
+		{name: "missing topic", consumerGroup: "workers", topicName: "missing", expected: 0, partitions: 7},
+	}
+
+	server := burrowServer()
+	defer server.Close()
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewKafkaLagMetric(server.URL, "local", tc.consumerGroup, tc.topicName, tc.lagMode)
+			// If anything goes wrong we should keep the last values
+			m.currentVal = 99
+			m.partitions = 7
+			m.UpdateCurrent()
+
+			if m.Current() != tc.expected {
+				t.Errorf("Expected lag %d but got %d", tc.expected, m.Current())
+			}
+
+			if m.MaxContainers() != tc.partitions {
+				t.Errorf("Expected %d partitions but got %d", tc.partitions, m.MaxContainers())
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ package metric
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/microscaling/microscaling/utils"
 )
@@ -13,12 +15,18 @@ const constNSQStatsAPI string = "/stats?format=json"
 
 // compile-time assert that we implement the right interface
 var _ Metric = (*NSQMetric)(nil)
+var _ RateReporter = (*NSQMetric)(nil)
 
 // NSQMetric stores the current value.
 type NSQMetric struct {
 	currentVal  int
+	updated     time.Time
 	topicName   string
 	channelName string
+	finished    int64
+	rate        float64
+	rateSince   time.Time
+	rateOK      bool
 }
 
 // StatsMessage from NSQ stats API.
@@ -39,8 +47,17 @@ type Topic struct {
 
 // Channel from NSQ stats API.
 type Channel struct {
-	ChannelName string `json:"channel_name"`
-	Depth       int    `json:"depth"`
+	ChannelName   string `json:"channel_name"`
+	Depth         int    `json:"depth"`
+	InFlightCount int    `json:"in_flight_count"`
+	DeferredCount int    `json:"deferred_count"`
+	MessageCount  int64  `json:"message_count"`
+}
+
+// finished is how many messages have been taken off the channel and not put back. Requeued messages go back
+// into the depth or the deferred count without being counted again, so they aren't included.
+func (c Channel) finished() int64 {
+	return c.MessageCount - int64(c.Depth+c.InFlightCount+c.DeferredCount)
 }
 
 var (
@@ -72,18 +89,18 @@ func NewNSQMetric(topicName string, channelName string) *NSQMetric {
 }
 
 // UpdateCurrent sets the current queue length.
-func (nsqm *NSQMetric) UpdateCurrent() {
+func (nsqm *NSQMetric) UpdateCurrent() error {
 	var statsMessage StatsMessage
 
 	url := "http://" + nsqStatsEndpoint + constNSQStatsAPI
 	body, err := utils.GetJSON(url)
 	if err != nil {
-		log.Errorf("Error getting NSQ metric %v", err)
+		return fmt.Errorf("Error getting NSQ metric %v", err)
 	}
 
 	err = json.Unmarshal(body, &statsMessage)
 	if err != nil {
-		log.Errorf("Error %v unmarshalling from %s", err, string(body[:]))
+		return fmt.Errorf("Error %v unmarshalling from %s", err, string(body[:]))
 	}
 
 	// Loop through NSQ Channels and Metrics to find the correct value.
@@ -91,16 +108,46 @@ func (nsqm *NSQMetric) UpdateCurrent() {
 		if topic.TopicName == nsqm.topicName {
 			for _, channel := range topic.Channels {
 				if channel.ChannelName == nsqm.channelName {
+					now := time.Now()
+					nsqm.updateRate(channel.finished(), now)
 					nsqm.currentVal = channel.Depth
+					nsqm.updated = now
+					log.Debugf("Topic: %s Channel: %s Length: %d", nsqm.topicName, nsqm.channelName, nsqm.currentVal)
+					return nil
 				}
 			}
 		}
 	}
 
-	log.Debugf("Topic: %s Channel: %s Length: %d", nsqm.topicName, nsqm.channelName, nsqm.currentVal)
+	return fmt.Errorf("NSQ topic %s channel %s not found", nsqm.topicName, nsqm.channelName)
+}
+
+// updateRate works out how quickly messages are being finished since we last looked. The counts start
+// again from zero if nsqd restarts, so we need another reading after that.
+func (nsqm *NSQMetric) updateRate(finished int64, now time.Time) {
+	elapsed := now.Sub(nsqm.updated).Seconds()
+	if !nsqm.updated.IsZero() && elapsed > 0 && finished >= nsqm.finished {
+		nsqm.rate = float64(finished-nsqm.finished) / elapsed
+		nsqm.rateSince = nsqm.updated
+		nsqm.rateOK = true
+	} else {
+		nsqm.rateOK = false
+	}
+
+	nsqm.finished = finished
+}
+
+// DequeueRate returns how many messages per second were finished between the last two readings.
+func (nsqm *NSQMetric) DequeueRate() (float64, time.Time, bool) {
+	return nsqm.rate, nsqm.rateSince, nsqm.rateOK
 }
 
 // Current returns the queue length.
 func (nsqm *NSQMetric) Current() int {
 	return nsqm.currentVal
 }
+
+// Updated returns when we last got the queue length successfully.
+func (nsqm *NSQMetric) Updated() time.Time {
+	return nsqm.updated
+}
@@ -0,0 +1,63 @@
+package metric
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNSQDequeueRate(t *testing.T) {
+	var depth, messageCount int
+
+	// Stand-in for the nsqd stats API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data":{"topics":[{"topic_name":"demo","channels":[{"channel_name":"demo","depth":%d,"in_flight_count":5,"deferred_count":0,"message_count":%d}]}]}}`, depth, messageCount)
+	}))
+	defer server.Close()
+
+	NSQInit()
+	nsqStatsEndpoint = strings.TrimPrefix(server.URL, "http://")
+	defer NSQInit()
+
+	m := NewNSQMetric("demo", "demo")
+	depth, messageCount = 100, 1000
+	if err := m.UpdateCurrent(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if m.Current() != 100 {
+		t.Fatalf("Expected length 100 but was %d", m.Current())
+	}
+
+	if _, _, ok := m.DequeueRate(); ok {
+		t.Fatalf("Can't have a rate from one reading")
+	}
+
+	// Pretend the last reading was 10s ago. 200 more messages arrived and the queue went down by 50.
+	m.updated = m.updated.Add(-10 * time.Second)
+	previous := m.updated
+	depth, messageCount = 50, 1200
+	if err := m.UpdateCurrent(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	rate, since, ok := m.DequeueRate()
+	if !ok || rate < 24.9 || rate > 25.1 {
+		t.Fatalf("Expected rate 25/s but was %f (%t)", rate, ok)
+	}
+
+	if !since.Equal(previous) {
+		t.Fatalf("Expected the rate to be since the last reading at %v but was %v", previous, since)
+	}
+
+	// nsqd restarted, so the counts went back to zero
+	m.updated = m.updated.Add(-10 * time.Second)
+	depth, messageCount = 10, 20
+	m.UpdateCurrent()
+	if _, _, ok := m.DequeueRate(); ok {
+		t.Fatalf("Shouldn't have a rate after the counts were reset")
+	}
+}
@@ -1,5 +1,9 @@
 package metric
 
+import (
+	"time"
+)
+
 // NullMetric for cases such as Remainder rules, where we don't need to actually measure a current value
 type NullMetric struct{}
 
@@ -12,9 +16,14 @@ func NewNullMetric() *NullMetric {
 }
 
 // UpdateCurrent reads the value of the current metric, but this is a no-op for the Null metric
-func (n *NullMetric) UpdateCurrent() {}
+func (n *NullMetric) UpdateCurrent() error { return nil }
 
 // Current reads out the value of the current queue length - which is always 0 for the Null metric
 func (n *NullMetric) Current() int {
 	return 0
 }
+
+// Updated is always now for the Null metric, as there's nothing to get out of date
+func (n *NullMetric) Updated() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,75 @@
+package metric
+
+import (
+	"math"
+	"time"
+
+	"github.com/microscaling/microscaling/forecast"
+)
+
+// PredictiveMetric wraps another metric, and reports what we forecast it will be rather than its current
+// value. That way the target acts on where the metric is heading, so containers have time to start.
+type PredictiveMetric struct {
+	metric     Metric
+	forecaster *forecast.HoltWinters
+}
+
+// compile-time assert that we implement the right interfaces
+var _ Metric = (*PredictiveMetric)(nil)
+var _ ContainerLimiter = (*PredictiveMetric)(nil)
+var _ RateReporter = (*PredictiveMetric)(nil)
+
+// NewPredictiveMetric forecasts the value of m
+func NewPredictiveMetric(m Metric, f *forecast.HoltWinters) *PredictiveMetric {
+	return &PredictiveMetric{
+		metric:     m,
+		forecaster: f,
+	}
+}
+
+// Forecaster returns the forecaster we're using
+func (p *PredictiveMetric) Forecaster() *forecast.HoltWinters {
+	return p.forecaster
+}
+
+// UpdateCurrent reads the underlying metric and adds it to the history we forecast from
+func (p *PredictiveMetric) UpdateCurrent() error {
+	err := p.metric.UpdateCurrent()
+	if err != nil {
+		return err
+	}
+
+	p.forecaster.Add(p.metric.Updated(), float64(p.metric.Current()))
+	return nil
+}
+
+// Current returns the forecast, or the current value until we have enough history to forecast
+func (p *PredictiveMetric) Current() int {
+	if !p.forecaster.Ready() {
+		return p.metric.Current()
+	}
+
+	f := math.Max(0, p.forecaster.Forecast())
+	return int(f + 0.5)
+}
+
+// Updated is when the underlying metric was last read
+func (p *PredictiveMetric) Updated() time.Time {
+	return p.metric.Updated()
+}
+
+// MaxContainers passes on the limit from the underlying metric, if it has one
+func (p *PredictiveMetric) MaxContainers() int {
+	if l, ok := p.metric.(ContainerLimiter); ok {
+		return l.MaxContainers()
+	}
+	return 0
+}
+
+// DequeueRate passes on the rate from the underlying metric, if it can measure one
+func (p *PredictiveMetric) DequeueRate() (float64, time.Time, bool) {
+	if r, ok := p.metric.(RateReporter); ok {
+		return r.DequeueRate()
+	}
+	return 0, time.Time{}, false
+}
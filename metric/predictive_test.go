@@ -0,0 +1,51 @@
+package metric
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/microscaling/microscaling/forecast"
+)
+
+func TestPredictiveMetric(t *testing.T) {
+	alpha, beta := 0.8, 0.8
+	f, err := forecast.New(forecast.Config{Horizon: "5s", Alpha: &alpha, Beta: &beta})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	m := NewToyMetric()
+	p := NewPredictiveMetric(m, f)
+	start := time.Now()
+
+	// Until we have enough history we just pass on the current value
+	m.SettableCurrent = 10
+	m.SettableUpdated = start
+	if err = p.UpdateCurrent(); err != nil || p.Current() != 10 {
+		t.Fatalf("Expected the current value 10 but got %d, %v", p.Current(), err)
+	}
+
+	// The queue is growing by 10 a second, so we expect it to be 50 more in 5 seconds
+	for i := 1; i <= 20; i++ {
+		m.SettableCurrent = 10 + 5*i
+		m.SettableUpdated = start.Add(time.Duration(i) * 500 * time.Millisecond)
+		if err = p.UpdateCurrent(); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if p.Current() != 160 {
+		t.Fatalf("Expected forecast 160 but got %d", p.Current())
+	}
+
+	// Errors are passed on, and we don't add anything to the history
+	m.SettableErr = errors.New("Connection refused")
+	if p.UpdateCurrent() == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if p.Current() != 160 {
+		t.Fatalf("Expected forecast to stay at 160 but got %d", p.Current())
+	}
+}
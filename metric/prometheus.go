@@ -0,0 +1,156 @@
+package metric
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/microscaling/microscaling/utils"
+)
+
+const constPrometheusEndpoint string = "http://127.0.0.1:9090"
+const constPrometheusQueryAPI string = "/api/v1/query"
+
+// compile-time assert that we implement the right interface
+var _ Metric = (*PrometheusMetric)(nil)
+
+// PrometheusMetric runs a PromQL query, so we can scale on anything Prometheus is already collecting.
+// The result must be a single value. It's rounded to an int, so multiply it up in the query if you need
+// more precision, e.g. to get latency in milliseconds rather than seconds.
+type PrometheusMetric struct {
+	currentVal int
+	updated    time.Time
+	endpoint   string
+	query      string
+}
+
+// prometheusResponse from the Prometheus query API
+type prometheusResponse struct {
+	Status    string         `json:"status"`
+	Data      prometheusData `json:"data"`
+	ErrorType string         `json:"errorType"`
+	Error     string         `json:"error"`
+}
+
+// prometheusData holds a query result. For a scalar the result is a single [time, value] pair, and for a
+// vector it's a list of series that each have a [time, value] pair.
+type prometheusData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+type prometheusSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+// NewPrometheusMetric creates the metric. If endpoint is empty we use PROMETHEUS_ENDPOINT, or the default local address.
+func NewPrometheusMetric(endpoint string, query string) *PrometheusMetric {
+	if endpoint == "" {
+		endpoint = os.Getenv("PROMETHEUS_ENDPOINT")
+	}
+
+	if endpoint == "" {
+		endpoint = constPrometheusEndpoint
+	}
+
+	return &PrometheusMetric{
+		endpoint: endpoint,
+		query:    query,
+	}
+}
+
+// UpdateCurrent runs the query and stores the result. If anything goes wrong we keep the last value.
+func (pm *PrometheusMetric) UpdateCurrent() error {
+	v, err := pm.runQuery()
+	if err != nil {
+		return fmt.Errorf("Error getting Prometheus metric for %s: %v", pm.query, err)
+	}
+
+	pm.currentVal = int(math.Floor(v + 0.5))
+	pm.updated = time.Now()
+	log.Debugf("Prometheus query %s: %f", pm.query, v)
+	return nil
+}
+
+// Current returns the result of the query.
+func (pm *PrometheusMetric) Current() int {
+	return pm.currentVal
+}
+
+// Updated returns when we last ran the query successfully.
+func (pm *PrometheusMetric) Updated() time.Time {
+	return pm.updated
+}
+
+func (pm *PrometheusMetric) runQuery() (v float64, err error) {
+	var resp prometheusResponse
+
+	body, err := utils.GetJSON(pm.endpoint + constPrometheusQueryAPI + "?query=" + url.QueryEscape(pm.query))
+	if err != nil {
+		return 0, err
+	}
+
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to decode response %s: %v", string(body), err)
+	}
+
+	if resp.Status != "success" {
+		return 0, fmt.Errorf("Query failed with %s: %s", resp.ErrorType, resp.Error)
+	}
+
+	var value []interface{}
+
+	switch resp.Data.ResultType {
+	case "scalar":
+		err = json.Unmarshal(resp.Data.Result, &value)
+		if err != nil {
+			return 0, fmt.Errorf("Failed to decode scalar: %v", err)
+		}
+	case "vector":
+		var samples []prometheusSample
+		err = json.Unmarshal(resp.Data.Result, &samples)
+		if err != nil {
+			return 0, fmt.Errorf("Failed to decode vector: %v", err)
+		}
+
+		if len(samples) != 1 {
+			return 0, fmt.Errorf("Expected a single value but got %d series", len(samples))
+		}
+
+		value = samples[0].Value
+	default:
+		return 0, fmt.Errorf("Unsupported result type %s", resp.Data.ResultType)
+	}
+
+	return parsePrometheusValue(value)
+}
+
+// parsePrometheusValue gets the value out of a [time, "value"] pair. Prometheus sends the value as a string
+// so that it can represent NaN and infinity.
+func parsePrometheusValue(value []interface{}) (v float64, err error) {
+	if len(value) != 2 {
+		return 0, fmt.Errorf("Unexpected value %v", value)
+	}
+
+	s, ok := value[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("Unexpected value %v", value[1])
+	}
+
+	v, err = strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Bad value %s: %v", s, err)
+	}
+
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("Value %s can't be used for scaling", s)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,94 @@
+package metric
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrometheusMetric(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected int
+	}{
+		{
+			name:     "vector",
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"queue":"demo"},"value":[1435781451.781,"41.6"]}]}}`,
+			expected: 42,
+		},
+		{
+			name:     "scalar",
+			response: `{"status":"success","data":{"resultType":"scalar","result":[1435781451.781,"7"]}}`,
+			expected: 7,
+		},
+		{
+			name:     "no series",
+			response: `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			expected: 5,
+		},
+		{
+			name:     "too many series",
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,"1"]},{"metric":{},"value":[1,"2"]}]}}`,
+			expected: 5,
+		},
+		{
+			name:     "not a number",
+			response: `{"status":"success","data":{"resultType":"scalar","result":[1435781451.781,"NaN"]}}`,
+			expected: 5,
+		},
+		{
+			name:     "matrix",
+			response: `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+			expected: 5,
+		},
+		{
+			name:     "error",
+			response: `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			expected: 5,
+		},
+		{
+			name:     "not json",
+			response: `Oops`,
+			expected: 5,
+		},
+	}
+
+	var query string
+	var response string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		query = r.URL.Query().Get("query")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			response = tc.response
+
+			m := NewPrometheusMetric(server.URL, `sum(rate(http_requests_total{job="web"}[1m]))`)
+			// If anything goes wrong we should keep the last value
+			m.currentVal = 5
+			m.UpdateCurrent()
+
+			if query != `sum(rate(http_requests_total{job="web"}[1m]))` {
+				t.Errorf("Query not passed correctly: %s", query)
+			}
+
+			if m.Current() != tc.expected {
+				t.Errorf("Expected %d but got %d", tc.expected, m.Current())
+			}
+		})
+	}
+}
+
+func TestPrometheusMetricDefaultEndpoint(t *testing.T) {
+	m := NewPrometheusMetric("", "up")
+	if m.endpoint != constPrometheusEndpoint {
+		t.Errorf("Unexpected endpoint %s", m.endpoint)
+	}
+}
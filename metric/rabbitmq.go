@@ -0,0 +1,130 @@
+package metric
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const constRabbitMQEndpoint string = "http://127.0.0.1:15672"
+const constRabbitMQUsername string = "guest"
+const constRabbitMQPassword string = "guest"
+
+// compile-time assert that we implement the right interface
+var _ Metric = (*RabbitMQMetric)(nil)
+
+// RabbitMQMetric measures the length of a RabbitMQ queue using the management HTTP API. By default we count
+// messages that are ready to be delivered, and we can also count messages that have been delivered but not yet
+// acknowledged, as they are still being worked on.
+type RabbitMQMetric struct {
+	client         *http.Client
+	currentVal     int
+	updated        time.Time
+	endpoint       string
+	username       string
+	password       string
+	vhost          string
+	queueName      string
+	includeUnacked bool
+}
+
+// rabbitMQQueue is the part of the management API's queue info that we use
+type rabbitMQQueue struct {
+	MessagesReady          int `json:"messages_ready"`
+	MessagesUnacknowledged int `json:"messages_unacknowledged"`
+}
+
+// NewRabbitMQMetric creates the metric. If endpoint is empty we use RABBITMQ_MANAGEMENT_ENDPOINT, or the
+// default local address. Credentials come from RABBITMQ_USERNAME and RABBITMQ_PASSWORD.
+func NewRabbitMQMetric(endpoint string, vhost string, queueName string, includeUnacked bool) *RabbitMQMetric {
+	if endpoint == "" {
+		endpoint = getEnvOrDefault("RABBITMQ_MANAGEMENT_ENDPOINT", constRabbitMQEndpoint)
+	}
+
+	if vhost == "" {
+		vhost = "/"
+	}
+
+	return &RabbitMQMetric{
+		client:         &http.Client{Timeout: 10 * time.Second},
+		endpoint:       endpoint,
+		username:       getEnvOrDefault("RABBITMQ_USERNAME", constRabbitMQUsername),
+		password:       getEnvOrDefault("RABBITMQ_PASSWORD", constRabbitMQPassword),
+		vhost:          vhost,
+		queueName:      queueName,
+		includeUnacked: includeUnacked,
+	}
+}
+
+// UpdateCurrent gets the number of messages in the queue. If anything goes wrong we keep the last value.
+func (rm *RabbitMQMetric) UpdateCurrent() error {
+	q, err := rm.getQueue()
+	if err != nil {
+		return fmt.Errorf("Error getting RabbitMQ metric for %s: %v", rm.queueName, err)
+	}
+
+	rm.currentVal = q.MessagesReady
+	if rm.includeUnacked {
+		rm.currentVal += q.MessagesUnacknowledged
+	}
+
+	rm.updated = time.Now()
+	log.Debugf("RabbitMQ vhost %s queue %s: ready %d, unacked %d", rm.vhost, rm.queueName, q.MessagesReady, q.MessagesUnacknowledged)
+	return nil
+}
+
+// Current returns the queue length.
+func (rm *RabbitMQMetric) Current() int {
+	return rm.currentVal
+}
+
+// Updated returns when we last got the queue length successfully.
+func (rm *RabbitMQMetric) Updated() time.Time {
+	return rm.updated
+}
+
+func (rm *RabbitMQMetric) getQueue() (q rabbitMQQueue, err error) {
+	// The default vhost is called "/" so it needs escaping too
+	u := rm.endpoint + "/api/queues/" + url.PathEscape(rm.vhost) + "/" + url.PathEscape(rm.queueName)
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return q, err
+	}
+
+	req.SetBasicAuth(rm.username, rm.password)
+	resp, err := rm.client.Do(req)
+	if err != nil {
+		return q, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return q, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return q, fmt.Errorf("GET %s failed with %s: %s", u, resp.Status, string(body))
+	}
+
+	err = json.Unmarshal(body, &q)
+	if err != nil {
+		return q, fmt.Errorf("Failed to decode response %s: %v", string(body), err)
+	}
+
+	return q, nil
+}
+
+func getEnvOrDefault(name string, defaultValue string) string {
+	v := os.Getenv(name)
+	if v == "" {
+		v = defaultValue
+	}
+
+	return v
+}
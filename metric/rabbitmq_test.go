@@ -0,0 +1,78 @@
+package metric
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRabbitMQMetric(t *testing.T) {
+	tests := []struct {
+		name           string
+		vhost          string
+		queueName      string
+		includeUnacked bool
+		expected       int
+	}{
+		{name: "ready", queueName: "jobs", expected: 12},
+		{name: "unacked", queueName: "jobs", includeUnacked: true, expected: 15},
+		{name: "vhost", vhost: "prod", queueName: "jobs", expected: 4},
+		{name: "missing queue", queueName: "missing", expected: 99},
+	}
+
+	os.Setenv("RABBITMQ_USERNAME", "scaler")
+	os.Setenv("RABBITMQ_PASSWORD", "secret")
+	defer os.Unsetenv("RABBITMQ_USERNAME")
+	defer os.Unsetenv("RABBITMQ_PASSWORD")
+
+	// Stand-in for the parts of the management API that we use
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "scaler" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.EscapedPath() {
+		case "/api/queues/%2F/jobs":
+			w.Write([]byte(`{"name":"jobs","vhost":"/","messages":15,"messages_ready":12,"messages_unacknowledged":3}`))
+		case "/api/queues/prod/jobs":
+			w.Write([]byte(`{"name":"jobs","vhost":"prod","messages":4,"messages_ready":4,"messages_unacknowledged":0}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"Object Not Found","reason":"Not Found"}`))
+		}
+	}))
+	defer server.Close()
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewRabbitMQMetric(server.URL, tc.vhost, tc.queueName, tc.includeUnacked)
+			// If anything goes wrong we should keep the last value
+			m.currentVal = 99
+			m.UpdateCurrent()
+
+			if m.Current() != tc.expected {
+				t.Errorf("Expected %d but got %d", tc.expected, m.Current())
+			}
+		})
+	}
+}
+
+func TestRabbitMQMetricBadCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		if user != "guest" {
+			t.Errorf("Expected default user but got %s", user)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	m := NewRabbitMQMetric(server.URL, "", "jobs", false)
+	_, err := m.getQueue()
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+}
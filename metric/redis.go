@@ -0,0 +1,211 @@
+package metric
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const constRedisAddress string = "127.0.0.1:6379"
+const constRedisTimeout = 5 * time.Second
+
+// Redis commands we can use to measure the amount of work waiting
+const (
+	RedisLLEN     = "LLEN"     // items in a list, e.g. for RQ or Sidekiq queues
+	RedisZCARD    = "ZCARD"    // items in a sorted set, e.g. for scheduled jobs
+	RedisXPENDING = "XPENDING" // messages delivered to a stream consumer group but not yet acknowledged
+)
+
+// compile-time assert that we implement the right interface
+var _ Metric = (*RedisMetric)(nil)
+
+// RedisMetric measures the length of a list, sorted set or stream in Redis
+type RedisMetric struct {
+	currentVal    int
+	updated       time.Time
+	address       string
+	password      string
+	db            int
+	command       string
+	key           string
+	consumerGroup string
+}
+
+// NewRedisMetric creates the metric. If address is empty we use REDIS_ADDRESS, or the default local address.
+// The password, if needed, comes from REDIS_PASSWORD. The consumer group is only needed for XPENDING.
+func NewRedisMetric(address string, db int, command string, key string, consumerGroup string) *RedisMetric {
+	if address == "" {
+		address = getEnvOrDefault("REDIS_ADDRESS", constRedisAddress)
+	}
+
+	if command == "" {
+		command = RedisLLEN
+	}
+
+	return &RedisMetric{
+		address:       address,
+		password:      getEnvOrDefault("REDIS_PASSWORD", ""),
+		db:            db,
+		command:       strings.ToUpper(command),
+		key:           key,
+		consumerGroup: consumerGroup,
+	}
+}
+
+// UpdateCurrent gets the length from Redis. If anything goes wrong we keep the last value.
+func (rm *RedisMetric) UpdateCurrent() error {
+	v, err := rm.getLength()
+	if err != nil {
+		return fmt.Errorf("Error getting Redis %s for %s: %v", rm.command, rm.key, err)
+	}
+
+	rm.currentVal = v
+	rm.updated = time.Now()
+	log.Debugf("Redis %s %s: %d", rm.command, rm.key, rm.currentVal)
+	return nil
+}
+
+// Current returns the length.
+func (rm *RedisMetric) Current() int {
+	return rm.currentVal
+}
+
+// Updated returns when we last got the length successfully.
+func (rm *RedisMetric) Updated() time.Time {
+	return rm.updated
+}
+
+// getLength opens a new connection each time, as we only need one command every few hundred milliseconds
+// and this way we don't have to worry about reconnecting
+func (rm *RedisMetric) getLength() (length int, err error) {
+	conn, err := net.DialTimeout("tcp", rm.address, constRedisTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(constRedisTimeout))
+	rc := &redisConn{w: conn, r: bufio.NewReader(conn)}
+
+	if rm.password != "" {
+		if _, err = rc.do("AUTH", rm.password); err != nil {
+			return 0, err
+		}
+	}
+
+	if rm.db != 0 {
+		if _, err = rc.do("SELECT", strconv.Itoa(rm.db)); err != nil {
+			return 0, err
+		}
+	}
+
+	var reply interface{}
+
+	switch rm.command {
+	case RedisLLEN, RedisZCARD:
+		reply, err = rc.do(rm.command, rm.key)
+	case RedisXPENDING:
+		reply, err = rc.do(rm.command, rm.key, rm.consumerGroup)
+
+		// The summary form of XPENDING starts with the number of pending messages
+		if items, ok := reply.([]interface{}); ok && len(items) > 0 {
+			reply = items[0]
+		}
+	default:
+		return 0, fmt.Errorf("Unsupported command %s", rm.command)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("Unexpected reply %v", reply)
+	}
+
+	return int(n), nil
+}
+
+// redisConn speaks just enough of the Redis protocol (RESP) for the commands we need
+type redisConn struct {
+	w io.Writer
+	r *bufio.Reader
+}
+
+// do sends a command and reads the reply
+func (rc *redisConn) do(args ...string) (reply interface{}, err error) {
+	err = writeRedisCommand(rc.w, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return readRedisReply(rc.r)
+}
+
+// writeRedisCommand sends a command as an array of bulk strings
+func writeRedisCommand(w io.Writer, args []string) error {
+	cmd := fmt.Sprintf("*%d\r\n", len(args))
+	for _, a := range args {
+		cmd += fmt.Sprintf("$%d\r\n%s\r\n", len(a), a)
+	}
+
+	_, err := io.WriteString(w, cmd)
+	return err
+}
+
+// readRedisReply returns a string, int64, nil or []interface{}. Redis error replies are returned as errors.
+func readRedisReply(r *bufio.Reader) (reply interface{}, err error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return nil, fmt.Errorf("Empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("Redis error: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+
+		b := make([]byte, n+2)
+		_, err = io.ReadFull(r, b)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(b[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i], err = readRedisReply(r)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("Unexpected reply %s", line)
+	}
+}
@@ -0,0 +1,189 @@
+package metric
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+// redisStandIn is a minimal in-process Redis server that replies to the commands we send
+type redisStandIn struct {
+	listener net.Listener
+	password string
+	replies  map[string]string
+}
+
+func newRedisStandIn(t *testing.T, password string, replies map[string]string) *redisStandIn {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	s := &redisStandIn{listener: l, password: password, replies: replies}
+	go s.serve()
+	return s
+}
+
+func (s *redisStandIn) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *redisStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+	db := "0"
+
+	for {
+		cmd, err := readRedisReply(r)
+		if err != nil {
+			return
+		}
+
+		items := cmd.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = item.(string)
+		}
+
+		var reply string
+		switch {
+		case args[0] == "AUTH":
+			if args[1] == s.password {
+				authed = true
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case args[0] == "SELECT":
+			db = args[1]
+			reply = "+OK\r\n"
+		default:
+			var ok bool
+			reply, ok = s.replies[db+" "+strings.Join(args, " ")]
+			if !ok {
+				reply = "-ERR unknown command\r\n"
+			}
+		}
+
+		io.WriteString(conn, reply)
+	}
+}
+
+func (s *redisStandIn) Close() {
+	s.listener.Close()
+}
+
+func TestRedisMetric(t *testing.T) {
+	tests := []struct {
+		name          string
+		db            int
+		command       string
+		key           string
+		consumerGroup string
+		expected      int
+	}{
+		{name: "default list", key: "rq:queue:default", expected: 12},
+		{name: "list", command: "llen", key: "rq:queue:default", expected: 12},
+		{name: "sorted set", command: RedisZCARD, key: "schedule", expected: 3},
+		{name: "stream", command: RedisXPENDING, key: "events", consumerGroup: "workers", expected: 7},
+		{name: "other db", db: 2, command: RedisLLEN, key: "queue:default", expected: 40},
+		{name: "missing stream group", command: RedisXPENDING, key: "events", consumerGroup: "missing", expected: 99},
+		{name: "wrong type", command: RedisLLEN, key: "greeting", expected: 99},
+		{name: "bad command", command: "GET", key: "greeting", expected: 99},
+	}
+
+	os.Setenv("REDIS_PASSWORD", "secret")
+	defer os.Unsetenv("REDIS_PASSWORD")
+
+	s := newRedisStandIn(t, "secret", map[string]string{
+		"0 LLEN rq:queue:default":   ":12\r\n",
+		"0 ZCARD schedule":          ":3\r\n",
+		"0 XPENDING events workers": "*4\r\n:7\r\n$15\r\n1526569495631-0\r\n$15\r\n1526569498055-0\r\n*1\r\n*2\r\n$6\r\nworker\r\n$1\r\n7\r\n",
+		"0 XPENDING events missing": "-NOGROUP No such key 'events' or consumer group 'missing'\r\n",
+		"0 LLEN greeting":           "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		"2 LLEN queue:default":      ":40\r\n",
+	})
+	defer s.Close()
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewRedisMetric(s.listener.Addr().String(), tc.db, tc.command, tc.key, tc.consumerGroup)
+			// If anything goes wrong we should keep the last value
+			m.currentVal = 99
+			m.UpdateCurrent()
+
+			if m.Current() != tc.expected {
+				t.Errorf("Expected %d but got %d", tc.expected, m.Current())
+			}
+		})
+	}
+}
+
+func TestRedisMetricWrongPassword(t *testing.T) {
+	s := newRedisStandIn(t, "secret", map[string]string{"0 LLEN jobs": ":1\r\n"})
+	defer s.Close()
+
+	m := NewRedisMetric(s.listener.Addr().String(), 0, RedisLLEN, "jobs", "")
+	_, err := m.getLength()
+	if err == nil || !strings.Contains(err.Error(), "NOAUTH") {
+		t.Fatalf("Expected an auth error but got %v", err)
+	}
+
+	m.password = "guess"
+	_, err = m.getLength()
+	if err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatalf("Expected a wrong password error but got %v", err)
+	}
+}
+
+func TestRedisMetricNoServer(t *testing.T) {
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := l.Addr().String()
+	l.Close()
+
+	m := NewRedisMetric(addr, 0, RedisLLEN, "jobs", "")
+	_, err := m.getLength()
+	if err == nil {
+		t.Fatalf("Expected an error with no server")
+	}
+}
+
+func TestReadRedisReply(t *testing.T) {
+	tests := []struct {
+		reply    string
+		expected string
+	}{
+		{"+OK\r\n", "OK"},
+		{":42\r\n", "42"},
+		{"$5\r\nhello\r\n", "hello"},
+		{"$-1\r\n", "<nil>"},
+		{"*2\r\n:1\r\n$2\r\nhi\r\n", "[1 hi]"},
+		{"*-1\r\n", "<nil>"},
+	}
+
+	for _, tc := range tests {
+		reply, err := readRedisReply(bufio.NewReader(strings.NewReader(tc.reply)))
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tc.reply, err)
+			continue
+		}
+
+		if fmt.Sprintf("%v", reply) != tc.expected {
+			t.Errorf("Expected %s for %q but got %v", tc.expected, tc.reply, reply)
+		}
+	}
+}
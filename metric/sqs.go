@@ -2,8 +2,11 @@ package metric
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"path"
 	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -15,12 +18,21 @@ const constQueueLengthAttribute string = "ApproximateNumberOfMessages"
 
 // compiletime assert that we implement the right interface
 var _ Metric = (*SQSMetric)(nil)
+var _ RateReporter = (*SQSMetric)(nil)
 
 // SQSMetric is used to measure the length of an SQS Queue
 type SQSMetric struct {
 	client     sqsiface.SQSAPI
 	currentVal int
+	updated    time.Time
 	queueURL   string
+
+	// CloudWatch tells us how many messages are being deleted, which is how fast the queue is being worked through
+	deleted     deletedCounter
+	rate        float64
+	rateSince   time.Time
+	rateOK      bool
+	rateChecked time.Time
 }
 
 // NewSQSMetric makes sure we have access to the SQS client
@@ -41,13 +53,14 @@ func NewSQSMetric(queueURL string) (metric *SQSMetric, err error) {
 	metric = &SQSMetric{
 		client:   sqs.New(sess),
 		queueURL: queueURL,
+		deleted:  newCloudWatchClient(sess),
 	}
 
 	return
 }
 
 // UpdateCurrent calls the SQS API to get the queue length and stores the value in the metric.
-func (sm *SQSMetric) UpdateCurrent() {
+func (sm *SQSMetric) UpdateCurrent() error {
 	a := make([]*string, 1)
 	a[0] = aws.String(constQueueLengthAttribute)
 
@@ -58,19 +71,63 @@ func (sm *SQSMetric) UpdateCurrent() {
 
 	m, err := sm.client.GetQueueAttributes(&params)
 	if err != nil {
-		log.Errorf("Failed to get SQS queue info: %v", err)
+		return fmt.Errorf("Failed to get SQS queue info: %v", err)
 	}
 
 	v := aws.StringValue(m.Attributes[constQueueLengthAttribute])
-	sm.currentVal, err = strconv.Atoi(v)
+	length, err := strconv.Atoi(v)
 	if err != nil {
-		log.Errorf("Failed to convert queue length to int: %v", err)
-	} else {
-		log.Debugf("Queue URL %s length %d", sm.queueURL, sm.currentVal)
+		return fmt.Errorf("Failed to convert queue length to int: %v", err)
 	}
+
+	sm.currentVal = length
+	sm.updated = time.Now()
+	log.Debugf("Queue URL %s length %d", sm.queueURL, sm.currentVal)
+
+	sm.updateRate(sm.updated)
+	return nil
+}
+
+// updateRate gets the number of messages deleted from CloudWatch. It's only published every minute so there's
+// no point asking more often than that. Failing to get it doesn't stop us using the queue length, as we
+// can manage without a rate.
+func (sm *SQSMetric) updateRate(now time.Time) {
+	if sm.deleted == nil || now.Sub(sm.rateChecked) < sqsMetricPeriod {
+		return
+	}
+
+	sm.rateChecked = now
+	queueName := path.Base(sm.queueURL)
+	count, start, ok, err := sm.deleted.MessagesDeleted(queueName, now)
+	if err != nil {
+		log.Warningf("Failed to get messages deleted for SQS queue %s from CloudWatch: %v", queueName, err)
+		return
+	}
+
+	if !ok {
+		log.Debugf("No CloudWatch data for SQS queue %s", queueName)
+		sm.rateOK = false
+		return
+	}
+
+	sm.rate = count / sqsMetricPeriod.Seconds()
+	sm.rateSince = start
+	sm.rateOK = true
+	log.Debugf("Queue %s dequeue rate %f/s", queueName, sm.rate)
+}
+
+// DequeueRate returns how many messages per second were deleted from the queue in the last period CloudWatch
+// published. That can be several minutes ago, so we say when it started.
+func (sm *SQSMetric) DequeueRate() (float64, time.Time, bool) {
+	return sm.rate, sm.rateSince, sm.rateOK
 }
 
 // Current reads out the value of the current queue length
 func (sm *SQSMetric) Current() int {
 	return sm.currentVal
 }
+
+// Updated returns when we last got the queue length successfully
+func (sm *SQSMetric) Updated() time.Time {
+	return sm.updated
+}
@@ -1,8 +1,10 @@
 package metric
 
 import (
+	"errors"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sqs"
@@ -24,6 +26,15 @@ func (m mockedQueueAttributes) GetQueueAttributes(in *sqs.GetQueueAttributesInpu
 	return &m.Resp, nil
 }
 
+type failingQueueAttributes struct {
+	sqsiface.SQSAPI
+}
+
+// Mock SQS API call that fails, returning nil as the real client does
+func (m failingQueueAttributes) GetQueueAttributes(in *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+	return nil, errors.New("AccessDenied")
+}
+
 func TestUpdateCurrent(t *testing.T) {
 	queueURL := "https://sqs.us-east-1.amazonaws.com/1234567890/microscaling-test"
 	cases := []sqsTest{
@@ -43,11 +54,106 @@ func TestUpdateCurrent(t *testing.T) {
 			queueURL: queueURL,
 		}
 
-		m.UpdateCurrent()
+		err := m.UpdateCurrent()
+		if err != nil {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+		}
 
 		if m.Current() != c.Expected {
 			t.Errorf("Test %d: expected count %d but was %d", i, c.Expected, m.Current())
 		}
+
+		if time.Since(m.Updated()) > time.Second {
+			t.Errorf("Test %d: updated time not set", i)
+		}
+	}
+}
+
+func TestUpdateCurrentError(t *testing.T) {
+	m := SQSMetric{
+		client:     failingQueueAttributes{},
+		queueURL:   "https://sqs.us-east-1.amazonaws.com/1234567890/microscaling-test",
+		currentVal: 42,
+	}
+
+	err := m.UpdateCurrent()
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if m.Current() != 42 {
+		t.Errorf("Expected to keep the last value but was %d", m.Current())
+	}
+
+	if !m.Updated().IsZero() {
+		t.Errorf("Shouldn't have an updated time without a successful read")
+	}
+
+	m.client = mockedQueueAttributes{Resp: sqs.GetQueueAttributesOutput{}}
+	err = m.UpdateCurrent()
+	if err == nil {
+		t.Fatalf("Expected an error for a missing attribute")
+	}
+}
+
+type mockedDeleted struct {
+	count float64
+	ok    bool
+	err   error
+	calls int
+}
+
+func (m *mockedDeleted) MessagesDeleted(queueName string, now time.Time) (float64, time.Time, bool, error) {
+	m.calls++
+	return m.count, now.Add(-2 * time.Minute), m.ok, m.err
+}
+
+func TestSQSDequeueRate(t *testing.T) {
+	deleted := &mockedDeleted{count: 600, ok: true}
+	m := SQSMetric{
+		client:   mockedQueueAttributes{Resp: getQueueAttributes(42)},
+		queueURL: "https://sqs.us-east-1.amazonaws.com/1234567890/microscaling-test",
+		deleted:  deleted,
+	}
+
+	if err := m.UpdateCurrent(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	rate, since, ok := m.DequeueRate()
+	if !ok || rate != 10 {
+		t.Fatalf("Expected rate 10/s but was %f (%t)", rate, ok)
+	}
+
+	// The rate is from a while ago
+	if !since.Equal(m.rateChecked.Add(-2 * time.Minute)) {
+		t.Fatalf("Expected the rate to be from when the CloudWatch period started but was %v", since)
+	}
+
+	// CloudWatch only has new data every minute
+	deleted.count = 1200
+	m.UpdateCurrent()
+	if rate, _, _ := m.DequeueRate(); rate != 10 || deleted.calls != 1 {
+		t.Fatalf("Shouldn't have asked CloudWatch again so soon")
+	}
+
+	// Failing to get the rate keeps the last one, and doesn't stop us getting the queue length
+	m.rateChecked = m.rateChecked.Add(-time.Minute)
+	deleted.err = errors.New("AccessDenied")
+	if err := m.UpdateCurrent(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if rate, _, ok := m.DequeueRate(); !ok || rate != 10 {
+		t.Fatalf("Expected to keep rate 10/s but was %f (%t)", rate, ok)
+	}
+
+	m.rateChecked = m.rateChecked.Add(-time.Minute)
+	deleted.err = nil
+	deleted.ok = false
+	m.UpdateCurrent()
+	if _, _, ok := m.DequeueRate(); ok {
+		t.Fatalf("Shouldn't have a rate without any data")
 	}
 }
 
@@ -1,8 +1,15 @@
 package metric
 
-// ToyMetric is only used for testing, but we can set its value
+import (
+	"time"
+)
+
+// ToyMetric is only used for testing, but we can set its value, whether updating it fails, and when it
+// was last updated
 type ToyMetric struct {
 	SettableCurrent int
+	SettableErr     error
+	SettableUpdated time.Time
 }
 
 // compile-time assert that we implement the right interface
@@ -14,9 +21,20 @@ func NewToyMetric() *ToyMetric {
 }
 
 // UpdateCurrent reads the value of the current metric, but this is a no-op for the Toy metric
-func (t *ToyMetric) UpdateCurrent() {}
+func (t *ToyMetric) UpdateCurrent() error {
+	return t.SettableErr
+}
 
 // Current reads out the value of the current queue length
 func (t *ToyMetric) Current() int {
 	return t.SettableCurrent
 }
+
+// Updated returns SettableUpdated, or now if that hasn't been set, so a toy metric is up to date unless we say otherwise
+func (t *ToyMetric) Updated() time.Time {
+	if t.SettableUpdated.IsZero() {
+		return time.Now()
+	}
+
+	return t.SettableUpdated
+}
@@ -13,4 +13,14 @@ type Monitor interface {
 	SendMetrics(tasks *demand.Tasks) (err error)
 }
 
+// Recorder is implemented by monitors that want to hear about scaling operations as they happen, as well
+// as getting the state of tasks on a regular basis
+type Recorder interface {
+	// ScaledTask is called when the number of containers requested for a task changes
+	ScaledTask(name string, from int, to int)
+
+	// SchedulerError is called when the scheduler fails to stop or start tasks
+	SchedulerError(err error)
+}
+
 var log = logging.MustGetLogger("mssmonitor")
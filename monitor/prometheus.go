@@ -0,0 +1,191 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/target"
+)
+
+// PrometheusMonitor serves the latest state of tasks on /metrics in the Prometheus text format, so you can
+// scrape microscaling alongside everything else you're monitoring
+type PrometheusMonitor struct {
+	sync.Mutex
+	tasks           []taskState
+	scaleUps        map[string]int
+	scaleDowns      map[string]int
+	schedulerErrors int
+}
+
+// taskState is what we last heard about a task
+type taskState struct {
+	name            string
+	demand          int
+	requested       int
+	running         int
+	idealContainers int
+	metric          int
+	target          int
+	hasTarget       bool
+}
+
+// gauge is a per-task value that we expose
+type gauge struct {
+	name  string
+	help  string
+	value func(ts taskState) int
+}
+
+var gauges = []gauge{
+	{"microscaling_task_demand", "Number of containers the demand engine wants for the task.", func(ts taskState) int { return ts.demand }},
+	{"microscaling_task_requested", "Number of containers requested from the scheduler for the task.", func(ts taskState) int { return ts.requested }},
+	{"microscaling_task_running", "Number of containers running for the task.", func(ts taskState) int { return ts.running }},
+	{"microscaling_task_ideal_containers", "Number of containers the task would have if there were no other tasks.", func(ts taskState) int { return ts.idealContainers }},
+	{"microscaling_task_metric", "Current value of the metric for the task.", func(ts taskState) int { return ts.metric }},
+}
+
+// compile-time assert that we implement the right interfaces
+var _ Monitor = (*PrometheusMonitor)(nil)
+var _ Recorder = (*PrometheusMonitor)(nil)
+var _ http.Handler = (*PrometheusMonitor)(nil)
+
+// NewPrometheusMonitor returns a new monitor that exposes metrics about tasks for Prometheus to scrape
+func NewPrometheusMonitor() *PrometheusMonitor {
+	return &PrometheusMonitor{
+		scaleUps:   make(map[string]int),
+		scaleDowns: make(map[string]int),
+	}
+}
+
+// Listen starts serving /metrics on this port in the background
+func (m *PrometheusMonitor) Listen(port string) error {
+	l, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("Failed to listen for Prometheus on port %s: %v", port, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+
+	go func() {
+		err := http.Serve(l, mux)
+		log.Errorf("Stopped serving Prometheus metrics: %v", err)
+	}()
+
+	return nil
+}
+
+// SendMetrics takes a copy of the current state of tasks, ready for the next scrape
+func (m *PrometheusMonitor) SendMetrics(tasks *demand.Tasks) error {
+	tasks.RLock()
+	state := make([]taskState, len(tasks.Tasks))
+	for i, task := range tasks.Tasks {
+		state[i] = taskState{
+			name:            task.Name,
+			demand:          task.Demand,
+			requested:       task.Requested,
+			running:         task.Running,
+			idealContainers: task.IdealContainers,
+		}
+
+		if task.Metric != nil {
+			state[i].metric = task.Metric.Current()
+		}
+
+		if sp, ok := task.Target.(target.SetPointer); ok {
+			state[i].target = sp.SetPoint()
+			state[i].hasTarget = true
+		}
+	}
+	tasks.RUnlock()
+
+	m.Lock()
+	m.tasks = state
+	m.Unlock()
+	return nil
+}
+
+// ScaledTask counts scale up and scale down operations for each task
+func (m *PrometheusMonitor) ScaledTask(name string, from int, to int) {
+	m.Lock()
+	defer m.Unlock()
+
+	if to > from {
+		m.scaleUps[name]++
+	} else if to < from {
+		m.scaleDowns[name]++
+	}
+}
+
+// SchedulerError counts the times the scheduler failed to stop or start tasks
+func (m *PrometheusMonitor) SchedulerError(err error) {
+	m.Lock()
+	m.schedulerErrors++
+	m.Unlock()
+}
+
+// ServeHTTP writes out all the metrics in the Prometheus text format
+func (m *PrometheusMonitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var b bytes.Buffer
+
+	m.Lock()
+	for _, g := range gauges {
+		writeHeader(&b, g.name, g.help, "gauge")
+		for _, ts := range m.tasks {
+			writeTaskValue(&b, g.name, ts.name, g.value(ts))
+		}
+	}
+
+	// Not every target has a value we're aiming for, e.g. a remainder target just wants as many containers as it can get
+	writeHeader(&b, "microscaling_task_target", "Value of the metric the task is aiming for.", "gauge")
+	for _, ts := range m.tasks {
+		if ts.hasTarget {
+			writeTaskValue(&b, "microscaling_task_target", ts.name, ts.target)
+		}
+	}
+
+	writeCounts(&b, "microscaling_scale_up_total", "Number of times the task has been scaled up.", m.scaleUps)
+	writeCounts(&b, "microscaling_scale_down_total", "Number of times the task has been scaled down.", m.scaleDowns)
+
+	writeHeader(&b, "microscaling_scheduler_errors_total", "Number of times the scheduler failed to stop or start tasks.", "counter")
+	fmt.Fprintf(&b, "microscaling_scheduler_errors_total %d\n", m.schedulerErrors)
+	m.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(b.Bytes())
+}
+
+func writeHeader(b *bytes.Buffer, name string, help string, metricType string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, metricType)
+}
+
+func writeTaskValue(b *bytes.Buffer, name string, taskName string, value int) {
+	fmt.Fprintf(b, "%s{task=\"%s\"} %d\n", name, escapeLabel(taskName), value)
+}
+
+// writeCounts writes a counter for each task, sorted so the output is stable between scrapes
+func writeCounts(b *bytes.Buffer, name string, help string, counts map[string]int) {
+	names := make([]string, 0, len(counts))
+	for taskName := range counts {
+		names = append(names, taskName)
+	}
+	sort.Strings(names)
+
+	writeHeader(b, name, help, "counter")
+	for _, taskName := range names {
+		writeTaskValue(b, name, taskName, counts[taskName])
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/metric"
+	"github.com/microscaling/microscaling/target"
+)
+
+func TestPrometheusMonitor(t *testing.T) {
+	var tasks demand.Tasks
+	tasks.Tasks = make([]*demand.Task, 2)
+
+	m := metric.NewToyMetric()
+	m.SettableCurrent = 42
+
+	tasks.Tasks[0] = &demand.Task{Name: "priority1", Demand: 8, Requested: 3, Running: 4, IdealContainers: 9,
+		Metric: m, Target: target.NewQueueLengthTarget(50)}
+	tasks.Tasks[1] = &demand.Task{Name: `say "hi"`, Demand: 2, Requested: 7, Running: 5,
+		Metric: metric.NewNullMetric(), Target: target.NewRemainderTarget(10)}
+
+	p := NewPrometheusMonitor()
+	err := p.SendMetrics(&tasks)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	p.ScaledTask("priority1", 3, 5)
+	p.ScaledTask("priority1", 5, 6)
+	p.ScaledTask("priority1", 6, 4)
+	p.SchedulerError(fmt.Errorf("Something went wrong"))
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	expected := []string{
+		"# TYPE microscaling_task_demand gauge",
+		`microscaling_task_demand{task="priority1"} 8`,
+		`microscaling_task_demand{task="say \"hi\""} 2`,
+		`microscaling_task_requested{task="priority1"} 3`,
+		`microscaling_task_running{task="say \"hi\""} 5`,
+		`microscaling_task_ideal_containers{task="priority1"} 9`,
+		`microscaling_task_metric{task="priority1"} 42`,
+		`microscaling_task_target{task="priority1"} 50`,
+		"# TYPE microscaling_scale_up_total counter",
+		`microscaling_scale_up_total{task="priority1"} 2`,
+		`microscaling_scale_down_total{task="priority1"} 1`,
+		"microscaling_scheduler_errors_total 1",
+	}
+
+	body := w.Body.String()
+	for _, e := range expected {
+		if !strings.Contains(body, e+"\n") {
+			t.Errorf("Expected metrics to contain %s but got\n%s", e, body)
+		}
+	}
+
+	// The remainder target doesn't have a value to aim for
+	if strings.Contains(body, `microscaling_task_target{task="say \"hi\""}`) {
+		t.Errorf("Didn't expect a target for the remainder task")
+	}
+}
+
+func TestPrometheusMonitorListen(t *testing.T) {
+	// Find a free port
+	l := httptest.NewServer(http.NotFoundHandler())
+	port := l.Listener.Addr().String()
+	port = port[strings.LastIndex(port, ":")+1:]
+	l.Close()
+
+	p := NewPrometheusMonitor()
+	err := p.Listen(port)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	resp, err := http.Get("http://localhost:" + port + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	b, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(b), "microscaling_scheduler_errors_total 0") {
+		t.Fatalf("Unexpected metrics %s", b)
+	}
+
+	// We can't listen twice on the same port
+	err = NewPrometheusMonitor().Listen(port)
+	if err == nil {
+		t.Fatalf("Expected an error listening on a port that's in use")
+	}
+}
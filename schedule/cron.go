@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSpec is a standard 5 field cron expression: minute, hour, day of month, month and day of week.
+// Each field can be *, a number, a range like 1-5, a step like */15 or 1-30/5, or a list of these.
+type cronSpec struct {
+	minute     []bool
+	hour       []bool
+	dayOfMonth []bool
+	month      []bool
+	dayOfWeek  []bool
+
+	// Like cron, if both days are restricted we match either of them
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// cronField describes the range of values for each field
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+func parseCron(expr string) (*cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("Cron expression %q should have %d fields but has %d", expr, len(cronFields), len(fields))
+	}
+
+	parsed := make([][]bool, len(fields))
+	for i, f := range fields {
+		values, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("Bad %s in cron expression %q: %v", cronFields[i].name, expr, err)
+		}
+		parsed[i] = values
+	}
+
+	// Sunday can be 0 or 7
+	if parsed[4][7] {
+		parsed[4][0] = true
+	}
+
+	return &cronSpec{
+		minute:        parsed[0],
+		hour:          parsed[1],
+		dayOfMonth:    parsed[2],
+		month:         parsed[3],
+		dayOfWeek:     parsed[4],
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}, nil
+}
+
+// parseCronField returns a slice where the values that match are set to true
+func parseCronField(field string, f cronField) ([]bool, error) {
+	values := make([]bool, f.max+1)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("Bad step %s", part[i+1:])
+			}
+			step = s
+			part = part[:i]
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("Bad range %s", part)
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("Bad range %s", part)
+			}
+		default:
+			v, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("Bad value %s", part)
+			}
+			lo, hi = v, v
+		}
+
+		if lo < f.min || hi > f.max || lo > hi {
+			return nil, fmt.Errorf("%s is out of range %d-%d", part, f.min, f.max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
+
+// matches returns true if the cron expression fires in the minute that t is in
+func (c *cronSpec) matches(t time.Time) bool {
+	if !c.minute[t.Minute()] || !c.hour[t.Hour()] || !c.month[int(t.Month())] {
+		return false
+	}
+
+	dom := c.dayOfMonth[t.Day()]
+	dow := c.dayOfWeek[int(t.Weekday())]
+
+	switch {
+	case c.anyDayOfMonth && c.anyDayOfWeek:
+		return true
+	case c.anyDayOfMonth:
+		return dow
+	case c.anyDayOfWeek:
+		return dom
+	default:
+		return dom || dow
+	}
+}
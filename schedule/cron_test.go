@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	bad := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	}
+
+	for _, expr := range bad {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// Monday 2 January 2017
+	monday := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr    string
+		t       time.Time
+		matches bool
+	}{
+		{"* * * * *", monday, true},
+		{"0 1 * * *", monday.Add(time.Hour), true},
+		{"0 1 * * *", monday.Add(time.Hour + time.Minute), false},
+		{"*/15 * * * *", monday.Add(45 * time.Minute), true},
+		{"*/15 * * * *", monday.Add(50 * time.Minute), false},
+		{"0 9 * * 1-5", monday.Add(9 * time.Hour), true},
+		{"0 9 * * 1-5", monday.Add(-15 * time.Hour), false}, // Sunday
+		{"0 0 * * 7", monday.Add(-24 * time.Hour), true},    // 7 is Sunday too
+		{"0 0 * * 0,6", monday, false},
+		{"0 0 2 1 *", monday, true},
+		{"0 0 3 * 1", monday, true}, // day of week matches even though day of month doesn't
+		{"0 0 3 * 2", monday, false},
+		{"0,30 8-10/2 * * *", monday.Add(10*time.Hour + 30*time.Minute), true},
+		{"0,30 8-10/2 * * *", monday.Add(9 * time.Hour), false},
+	}
+
+	for _, tc := range tests {
+		c, err := parseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", tc.expr, err)
+		}
+
+		if c.matches(tc.t) != tc.matches {
+			t.Errorf("Expected %q matching %v to be %t", tc.expr, tc.t, tc.matches)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package schedule overrides scaling settings for a task at particular times of day, for when we already
+// know the shape of the traffic
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// WindowConfig is a window in a task's schedule. The window starts each time the cron expression fires,
+// and lasts for the duration. Any of the overrides that are set apply while the window is open.
+type WindowConfig struct {
+	Cron     string `json:"cron"`     // e.g. "0 1 * * *" for 01:00 every day
+	Duration string `json:"duration"` // e.g. "4h"
+	Timezone string `json:"timezone"` // e.g. "Europe/London", defaults to local time
+
+	MinContainers *int `json:"minContainers,omitempty"`
+	MaxContainers *int `json:"maxContainers,omitempty"`
+	Priority      *int `json:"priority,omitempty"`
+	Containers    *int `json:"containers,omitempty"` // for tasks that use the Schedule rule type
+}
+
+// Override holds the settings from the windows that are open. Settings that aren't overridden are nil.
+type Override struct {
+	MinContainers *int
+	MaxContainers *int
+	Priority      *int
+	Containers    *int
+}
+
+// Schedule is a list of windows for a task
+type Schedule struct {
+	windows []window
+}
+
+type window struct {
+	cron     *cronSpec
+	duration time.Duration
+	location *time.Location
+	config   WindowConfig
+}
+
+// We check back this far at most for the start of an open window
+const maxWindowDuration = 7 * 24 * time.Hour
+
+// New creates a schedule from the window config
+func New(configs []WindowConfig) (*Schedule, error) {
+	s := &Schedule{}
+
+	for i, c := range configs {
+		w, err := newWindow(c)
+		if err != nil {
+			return nil, fmt.Errorf("Schedule window %d: %v", i, err)
+		}
+
+		s.windows = append(s.windows, w)
+	}
+
+	return s, nil
+}
+
+func newWindow(c WindowConfig) (w window, err error) {
+	w.config = c
+
+	w.cron, err = parseCron(c.Cron)
+	if err != nil {
+		return w, err
+	}
+
+	w.duration, err = time.ParseDuration(c.Duration)
+	if err != nil {
+		return w, fmt.Errorf("Bad duration %q: %v", c.Duration, err)
+	}
+
+	if w.duration < time.Minute || w.duration > maxWindowDuration {
+		return w, fmt.Errorf("Duration %v should be between 1m and %v", w.duration, maxWindowDuration)
+	}
+
+	w.location = time.Local
+	if c.Timezone != "" {
+		w.location, err = time.LoadLocation(c.Timezone)
+		if err != nil {
+			return w, fmt.Errorf("Bad timezone %q: %v", c.Timezone, err)
+		}
+	}
+
+	return w, nil
+}
+
+// open returns true if the window started less than its duration before now
+func (w window) open(now time.Time) bool {
+	start := now.In(w.location).Truncate(time.Minute)
+	for t := start; now.Sub(t) < w.duration; t = t.Add(-time.Minute) {
+		if w.cron.matches(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Active returns the overrides for the windows that are open. If more than one window is open and they
+// override the same setting, the one that's later in the schedule wins.
+func (s *Schedule) Active(now time.Time) (o Override) {
+	if s == nil {
+		return o
+	}
+
+	for _, w := range s.windows {
+		if !w.open(now) {
+			continue
+		}
+
+		if w.config.MinContainers != nil {
+			o.MinContainers = w.config.MinContainers
+		}
+		if w.config.MaxContainers != nil {
+			o.MaxContainers = w.config.MaxContainers
+		}
+		if w.config.Priority != nil {
+			o.Priority = w.config.Priority
+		}
+		if w.config.Containers != nil {
+			o.Containers = w.config.Containers
+		}
+	}
+
+	return o
+}
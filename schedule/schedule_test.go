@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestNew(t *testing.T) {
+	bad := []WindowConfig{
+		{Cron: "0 1 * *", Duration: "4h"},
+		{Cron: "0 1 * * *", Duration: "four hours"},
+		{Cron: "0 1 * * *", Duration: "10s"},
+		{Cron: "0 1 * * *", Duration: "4h", Timezone: "Nowhere/Special"},
+	}
+
+	for _, c := range bad {
+		if _, err := New([]WindowConfig{c}); err == nil {
+			t.Errorf("Expected an error for %v", c)
+		}
+	}
+}
+
+func TestActive(t *testing.T) {
+	s, err := New([]WindowConfig{
+		// Batch jobs overnight
+		{Cron: "0 1 * * *", Duration: "4h", Timezone: "UTC", MinContainers: intPtr(2), Priority: intPtr(1)},
+		// Business hours in New York
+		{Cron: "0 9 * * 1-5", Duration: "8h", Timezone: "America/New_York", MinContainers: intPtr(5), MaxContainers: intPtr(20)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Monday 2 January 2017, when New York is UTC-5
+	monday := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		min      *int
+		max      *int
+		priority *int
+	}{
+		{name: "before batch", now: monday.Add(59 * time.Minute)},
+		{name: "batch starts", now: monday.Add(time.Hour), min: intPtr(2), priority: intPtr(1)},
+		{name: "batch nearly done", now: monday.Add(5*time.Hour - time.Second), min: intPtr(2), priority: intPtr(1)},
+		{name: "batch done", now: monday.Add(5 * time.Hour)},
+		{name: "9am in London", now: monday.Add(9 * time.Hour)},
+		{name: "9am in New York", now: monday.Add(14 * time.Hour), min: intPtr(5), max: intPtr(20)},
+		{name: "5pm in New York", now: monday.Add(22 * time.Hour)},
+		{name: "Sunday in New York", now: monday.Add(-10 * time.Hour)},
+		{name: "Tuesday batch", now: monday.Add(26 * time.Hour), min: intPtr(2), priority: intPtr(1)},
+	}
+
+	for _, tc := range tests {
+		o := s.Active(tc.now)
+		if !sameInt(o.MinContainers, tc.min) || !sameInt(o.MaxContainers, tc.max) || !sameInt(o.Priority, tc.priority) {
+			t.Errorf("%s: unexpected override %v", tc.name, o)
+		}
+	}
+}
+
+func TestActiveOverlapping(t *testing.T) {
+	s, err := New([]WindowConfig{
+		{Cron: "0 * * * *", Duration: "1h", Timezone: "UTC", MinContainers: intPtr(1), Containers: intPtr(3)},
+		{Cron: "30 12 * * *", Duration: "1h", Timezone: "UTC", MinContainers: intPtr(4)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	o := s.Active(time.Date(2017, 1, 2, 13, 0, 0, 0, time.UTC))
+	if !sameInt(o.MinContainers, intPtr(4)) || !sameInt(o.Containers, intPtr(3)) {
+		t.Fatalf("Expected the later window to win, but got %v", o)
+	}
+
+	var none *Schedule
+	if o = none.Active(time.Now()); o.MinContainers != nil {
+		t.Fatalf("Expected no override without a schedule")
+	}
+}
+
+func sameInt(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
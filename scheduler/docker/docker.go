@@ -15,6 +15,10 @@ import (
 
 const labelMap string = "com.microscaling.microscaling-in-a-box"
 
+// Docker CPU shares are relative to 1024 for a whole CPU, and hard CPU limits are a quota of time per period
+const constCPUShares int64 = 1024
+const constCPUPeriod int64 = 100000 // microseconds
+
 var log = logging.MustGetLogger("mssscheduler")
 
 type dockerContainer struct {
@@ -46,8 +50,9 @@ func NewScheduler(pullImages bool, dockerHost string) *DockerScheduler {
 	}
 }
 
-// compile-time assert that we implement the right interface
+// compile-time assert that we implement the right interfaces
 var _ scheduler.Scheduler = (*DockerScheduler)(nil)
+var _ scheduler.CapacityDiscoverer = (*DockerScheduler)(nil)
 
 var scaling sync.WaitGroup
 
@@ -98,9 +103,17 @@ func (c *DockerScheduler) startTask(task *demand.Task) {
 		HostConfig: &docker.HostConfig{
 			PublishAllPorts: task.PublishAllPorts,
 			NetworkMode:     task.NetworkMode,
+			Memory:          task.Resources.Memory,
 		},
 	}
 
+	// Limit each container to the CPU we've accounted for it
+	if task.Resources.CPU > 0 {
+		createOpts.HostConfig.CPUShares = task.Resources.CPU * constCPUShares / 1000
+		createOpts.HostConfig.CPUPeriod = constCPUPeriod
+		createOpts.HostConfig.CPUQuota = task.Resources.CPU * constCPUPeriod / 1000
+	}
+
 	go func() {
 		scaling.Add(1)
 		defer scaling.Done()
@@ -294,7 +307,8 @@ func (c *DockerScheduler) CountAllTasks(running *demand.Tasks) error {
 			// log.Debugf("Found a container with labels %v", labels)
 			t, err := running.GetTask(taskName)
 			if err != nil {
-				log.Errorf("Received info about task %s that we're not managing", taskName)
+				// This can happen while config is being reloaded
+				log.Debugf("Received info about task %s that we're not managing", taskName)
 			} else {
 				newState := statusToState(containers[i].Status)
 				id := containers[i].ID[:12]
@@ -350,6 +364,18 @@ func (c *DockerScheduler) CountAllTasks(running *demand.Tasks) error {
 	return err
 }
 
+// DiscoverCapacity asks Docker how many CPUs and how much memory this host has
+func (c *DockerScheduler) DiscoverCapacity() (r demand.Resources, err error) {
+	info, err := c.client.Info()
+	if err != nil {
+		return r, fmt.Errorf("Failed to get Docker info: %v", err)
+	}
+
+	r.CPU = int64(info.NCPU) * 1000
+	r.Memory = info.MemTotal
+	return r, nil
+}
+
 // Cleanup gives the scheduler an opportunity to stop anything that needs to be stopped
 func (c *DockerScheduler) Cleanup() error {
 	return nil
@@ -0,0 +1,176 @@
+package ecs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// ECS isn't part of the AWS SDK we vendor, so this is a minimal client for the calls we need. The SDK
+// handles the session, signing and retries, and we handle the JSON.
+
+// ECSAPI is the part of the ECS API that we use, so that it can be mocked in tests
+type ECSAPI interface {
+	UpdateService(input *UpdateServiceInput) (*UpdateServiceOutput, error)
+	DescribeServices(input *DescribeServicesInput) (*DescribeServicesOutput, error)
+}
+
+// UpdateServiceInput changes the number of tasks for a service
+type UpdateServiceInput struct {
+	Cluster      string `json:"cluster,omitempty"`
+	Service      string `json:"service"`
+	DesiredCount int64  `json:"desiredCount"`
+}
+
+// UpdateServiceOutput is the service after it was updated
+type UpdateServiceOutput struct {
+	Service Service `json:"service"`
+}
+
+// DescribeServicesInput asks for up to 10 services
+type DescribeServicesInput struct {
+	Cluster  string   `json:"cluster,omitempty"`
+	Services []string `json:"services"`
+}
+
+// DescribeServicesOutput has the services that were found, and failures for the ones that weren't
+type DescribeServicesOutput struct {
+	Services []Service `json:"services"`
+	Failures []Failure `json:"failures"`
+}
+
+// Service from the ECS API
+type Service struct {
+	ServiceName  string `json:"serviceName"`
+	ServiceArn   string `json:"serviceArn"`
+	Status       string `json:"status"`
+	DesiredCount int64  `json:"desiredCount"`
+	RunningCount int64  `json:"runningCount"`
+	PendingCount int64  `json:"pendingCount"`
+}
+
+// Failure from the ECS API, e.g. a service that doesn't exist
+type Failure struct {
+	Arn    string `json:"arn"`
+	Reason string `json:"reason"`
+}
+
+const (
+	ecsServiceName  string = "ecs"
+	ecsTargetPrefix string = "AmazonEC2ContainerServiceV20141113"
+)
+
+type ecsClient struct {
+	*client.Client
+}
+
+// compile-time assert that we implement the right interface
+var _ ECSAPI = (*ecsClient)(nil)
+
+func newECSClient(p client.ConfigProvider, cfgs ...*aws.Config) *ecsClient {
+	c := p.ClientConfig(ecsServiceName, cfgs...)
+	e := &ecsClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   ecsServiceName,
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2014-11-13",
+				JSONVersion:   "1.1",
+				TargetPrefix:  ecsTargetPrefix,
+			},
+			c.Handlers,
+		),
+	}
+
+	e.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	e.Handlers.Build.PushBackNamed(request.NamedHandler{Name: "mss.ecs.Build", Fn: buildJSON})
+	e.Handlers.Unmarshal.PushBackNamed(request.NamedHandler{Name: "mss.ecs.Unmarshal", Fn: unmarshalJSON})
+	e.Handlers.UnmarshalMeta.PushBackNamed(request.NamedHandler{Name: "mss.ecs.UnmarshalMeta", Fn: unmarshalMeta})
+	e.Handlers.UnmarshalError.PushBackNamed(request.NamedHandler{Name: "mss.ecs.UnmarshalError", Fn: unmarshalError})
+	return e
+}
+
+func (e *ecsClient) send(name string, input interface{}, output interface{}) error {
+	op := &request.Operation{
+		Name:       name,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+
+	return e.NewRequest(op, input, output).Send()
+}
+
+// UpdateService sets the desired count for a service
+func (e *ecsClient) UpdateService(input *UpdateServiceInput) (*UpdateServiceOutput, error) {
+	output := &UpdateServiceOutput{}
+	return output, e.send("UpdateService", input, output)
+}
+
+// DescribeServices gets the counts for up to 10 services
+func (e *ecsClient) DescribeServices(input *DescribeServicesInput) (*DescribeServicesOutput, error) {
+	output := &DescribeServicesOutput{}
+	return output, e.send("DescribeServices", input, output)
+}
+
+// buildJSON sends the parameters as a JSON body, with the operation in a header
+func buildJSON(r *request.Request) {
+	body, err := json.Marshal(r.Params)
+	if err != nil {
+		r.Error = awserr.New("SerializationError", "failed encoding ECS request", err)
+		return
+	}
+
+	r.SetBufferBody(body)
+	r.HTTPRequest.Header.Set("X-Amz-Target", r.ClientInfo.TargetPrefix+"."+r.Operation.Name)
+	r.HTTPRequest.Header.Set("Content-Type", "application/x-amz-json-"+r.ClientInfo.JSONVersion)
+}
+
+func unmarshalJSON(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+	if !r.DataFilled() {
+		return
+	}
+
+	err := json.NewDecoder(r.HTTPResponse.Body).Decode(r.Data)
+	if err != nil {
+		r.Error = awserr.New("SerializationError", "failed decoding ECS response", err)
+	}
+}
+
+func unmarshalMeta(r *request.Request) {
+	r.RequestID = r.HTTPResponse.Header.Get("X-Amzn-Requestid")
+}
+
+// unmarshalError reads errors like {"__type": "com.amazonaws.ecs#ServiceNotFoundException", "message": "..."}
+func unmarshalError(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+
+	body, err := ioutil.ReadAll(r.HTTPResponse.Body)
+	if err != nil {
+		r.Error = awserr.New("SerializationError", "failed reading ECS error response", err)
+		return
+	}
+
+	var e struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+
+	if err := json.Unmarshal(body, &e); err != nil || e.Type == "" {
+		e.Type = "UnknownError"
+		e.Message = string(body)
+	}
+
+	code := e.Type[strings.LastIndex(e.Type, "#")+1:]
+	r.Error = awserr.NewRequestFailure(awserr.New(code, e.Message, nil), r.HTTPResponse.StatusCode, r.RequestID)
+}
@@ -0,0 +1,69 @@
+package ecs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestECSClient(t *testing.T) {
+	// Stand-in for the ECS API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-amz-json-1.1" {
+			t.Errorf("Unexpected content type %s", r.Header.Get("Content-Type"))
+		}
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonEC2ContainerServiceV20141113.UpdateService":
+			var input UpdateServiceInput
+			json.NewDecoder(r.Body).Decode(&input)
+			if input.Cluster != "test" || input.Service != "web" || input.DesiredCount != 0 {
+				t.Errorf("Unexpected input %+v", input)
+			}
+
+			w.Write([]byte(`{"service":{"serviceName":"web","status":"ACTIVE","desiredCount":0,"runningCount":2}}`))
+		case "AmazonEC2ContainerServiceV20141113.DescribeServices":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.ecs#ClusterNotFoundException","message":"Cluster not found."}`))
+		default:
+			t.Errorf("Unexpected target %s", r.Header.Get("X-Amz-Target"))
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	c := newECSClient(sess)
+	out, err := c.UpdateService(&UpdateServiceInput{Cluster: "test", Service: "web", DesiredCount: 0})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if out.Service.ServiceName != "web" || out.Service.RunningCount != 2 {
+		t.Fatalf("Unexpected output %+v", out)
+	}
+
+	_, err = c.DescribeServices(&DescribeServicesInput{Cluster: "test", Services: []string{"web"}})
+	aerr, ok := err.(awserr.RequestFailure)
+	if !ok {
+		t.Fatalf("Expected a request failure but got %v", err)
+	}
+
+	if aerr.Code() != "ClusterNotFoundException" || aerr.StatusCode() != http.StatusBadRequest {
+		t.Fatalf("Unexpected error %v", aerr)
+	}
+}
@@ -0,0 +1,169 @@
+// Package ecs provides a scheduler that scales Amazon ECS services.
+package ecs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/op/go-logging"
+
+	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/scheduler"
+)
+
+var log = logging.MustGetLogger("mssscheduler")
+
+// DescribeServices only takes this many services at a time
+const constDescribeServicesMax int = 10
+
+// ECSScheduler scales the services in an ECS cluster. Each task is the service with the same name.
+type ECSScheduler struct {
+	client  ECSAPI
+	cluster string
+}
+
+// NewScheduler returns a pointer to the scheduler, using the AWS region from the environment.
+func NewScheduler(cluster string) (*ECSScheduler, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		return nil, errors.New("AWS_REGION env var must be set")
+	}
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create AWS session: %v", err)
+	}
+
+	return &ECSScheduler{
+		client:  newECSClient(sess),
+		cluster: cluster,
+	}, nil
+}
+
+// compile-time assert that we implement the right interface
+var _ scheduler.Scheduler = (*ECSScheduler)(nil)
+
+// InitScheduler checks that there's an active service for the task.
+func (e *ECSScheduler) InitScheduler(task *demand.Task) error {
+	log.Infof("ECS initializing task %s", task.Name)
+
+	services, err := e.describeServices([]string{task.Name})
+	if err != nil {
+		return fmt.Errorf("Error getting service %s: %v", task.Name, err)
+	}
+
+	s, ok := services[task.Name]
+	if !ok || s.Status != "ACTIVE" {
+		return fmt.Errorf("No active service %s in cluster %s", task.Name, e.cluster)
+	}
+
+	return nil
+}
+
+// StopStartTasks by setting the desired count for each service.
+func (e *ECSScheduler) StopStartTasks(tasks *demand.Tasks) error {
+	// Create tasks if there aren't enough of them, and stop them if there are too many
+	var tooMany []*demand.Task
+	var tooFew []*demand.Task
+
+	tasks.Lock()
+	defer tasks.Unlock()
+
+	for _, task := range tasks.Tasks {
+		if task.Demand > task.Requested {
+			// There aren't enough of these containers yet
+			tooFew = append(tooFew, task)
+		}
+		if task.Demand < task.Requested {
+			// There are too many of these containers
+			tooMany = append(tooMany, task)
+		}
+	}
+
+	// Concatentate the two lists - scale down first to free up resources
+	tasksToScale := append(tooMany, tooFew...)
+	for _, task := range tasksToScale {
+		_, err := e.client.UpdateService(&UpdateServiceInput{
+			Cluster:      e.cluster,
+			Service:      task.Name,
+			DesiredCount: int64(task.Demand),
+		})
+		if err != nil {
+			log.Errorf("Couldn't scale %s: %v ", task.Name, err)
+			return err
+		}
+
+		task.Requested = task.Demand
+		log.Debugf("Now have %s: %d", task.Name, task.Requested)
+	}
+
+	return nil
+}
+
+// CountAllTasks tells us how many tasks of each service are currently running.
+func (e *ECSScheduler) CountAllTasks(running *demand.Tasks) error {
+	running.Lock()
+	defer running.Unlock()
+
+	names := make([]string, len(running.Tasks))
+	for i, task := range running.Tasks {
+		names[i] = task.Name
+	}
+
+	services, err := e.describeServices(names)
+	if err != nil {
+		log.Errorf("Error describing services: %v", err)
+		return err
+	}
+
+	// Set running counts. Defaults to 0 if the service does not exist. Pending tasks haven't started yet,
+	// so they don't count, but it's useful to know if they're stuck.
+	for _, task := range running.Tasks {
+		s := services[task.Name]
+		task.Running = int(s.RunningCount)
+		if s.PendingCount > 0 {
+			log.Debugf("Service %s has %d running and %d pending", task.Name, s.RunningCount, s.PendingCount)
+		}
+	}
+
+	return nil
+}
+
+// describeServices gets the services by name, in batches as large as the API allows. Services that
+// couldn't be found aren't in the map.
+func (e *ECSScheduler) describeServices(names []string) (map[string]Service, error) {
+	services := make(map[string]Service, len(names))
+
+	for start := 0; start < len(names); start += constDescribeServicesMax {
+		end := start + constDescribeServicesMax
+		if end > len(names) {
+			end = len(names)
+		}
+
+		out, err := e.client.DescribeServices(&DescribeServicesInput{
+			Cluster:  e.cluster,
+			Services: names[start:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range out.Services {
+			services[s.ServiceName] = s
+		}
+
+		for _, f := range out.Failures {
+			log.Debugf("Couldn't describe service %s: %s", f.Arn, f.Reason)
+		}
+	}
+
+	return services, nil
+}
+
+// Cleanup gives the scheduler an opportunity to stop anything that needs to be stopped
+func (e *ECSScheduler) Cleanup() error {
+	return nil
+}
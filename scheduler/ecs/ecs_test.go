@@ -0,0 +1,144 @@
+package ecs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/microscaling/microscaling/demand"
+)
+
+// mockECS keeps the desired and running counts for services in a cluster
+type mockECS struct {
+	services  map[string]*Service
+	describes int
+	updates   []string
+	failing   bool
+}
+
+func newMockECS(names ...string) *mockECS {
+	m := &mockECS{services: make(map[string]*Service)}
+	for _, name := range names {
+		m.services[name] = &Service{ServiceName: name, Status: "ACTIVE"}
+	}
+	return m
+}
+
+func (m *mockECS) UpdateService(input *UpdateServiceInput) (*UpdateServiceOutput, error) {
+	if m.failing {
+		return nil, errors.New("AccessDeniedException")
+	}
+
+	s, ok := m.services[input.Service]
+	if !ok {
+		return nil, fmt.Errorf("ServiceNotFoundException")
+	}
+
+	s.DesiredCount = input.DesiredCount
+	m.updates = append(m.updates, input.Service)
+	return &UpdateServiceOutput{Service: *s}, nil
+}
+
+func (m *mockECS) DescribeServices(input *DescribeServicesInput) (*DescribeServicesOutput, error) {
+	m.describes++
+	if len(input.Services) > constDescribeServicesMax {
+		return nil, fmt.Errorf("InvalidParameterException: too many services")
+	}
+
+	out := &DescribeServicesOutput{}
+	for _, name := range input.Services {
+		if s, ok := m.services[name]; ok {
+			out.Services = append(out.Services, *s)
+		} else {
+			out.Failures = append(out.Failures, Failure{Arn: name, Reason: "MISSING"})
+		}
+	}
+	return out, nil
+}
+
+func TestECSInitScheduler(t *testing.T) {
+	m := newMockECS("web")
+	m.services["old"] = &Service{ServiceName: "old", Status: "INACTIVE"}
+	e := &ECSScheduler{client: m, cluster: "test"}
+
+	if err := e.InitScheduler(&demand.Task{Name: "web"}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if err := e.InitScheduler(&demand.Task{Name: "old"}); err == nil {
+		t.Fatalf("Expected an error for an inactive service")
+	}
+
+	if err := e.InitScheduler(&demand.Task{Name: "missing"}); err == nil {
+		t.Fatalf("Expected an error for a missing service")
+	}
+}
+
+func TestECSCountAllTasks(t *testing.T) {
+	m := newMockECS()
+	tasks := &demand.Tasks{}
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("service-%d", i)
+		m.services[name] = &Service{ServiceName: name, Status: "ACTIVE", RunningCount: int64(i), PendingCount: 1}
+		tasks.Tasks = append(tasks.Tasks, &demand.Task{Name: name})
+	}
+
+	missing := &demand.Task{Name: "missing", Running: 3}
+	tasks.Tasks = append(tasks.Tasks, missing)
+
+	e := &ECSScheduler{client: m, cluster: "test"}
+	if err := e.CountAllTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if m.describes != 2 {
+		t.Fatalf("Expected to describe services in 2 batches but took %d", m.describes)
+	}
+
+	for i, task := range tasks.Tasks[:12] {
+		if task.Running != i {
+			t.Errorf("Expected %d running for %s but have %d", i, task.Name, task.Running)
+		}
+	}
+
+	if missing.Running != 0 {
+		t.Errorf("Expected nothing running for a missing service but have %d", missing.Running)
+	}
+}
+
+func TestECSStopStartTasks(t *testing.T) {
+	m := newMockECS("web", "worker", "batch")
+	e := &ECSScheduler{client: m, cluster: "test"}
+
+	web := &demand.Task{Name: "web", Demand: 5, Requested: 3}
+	worker := &demand.Task{Name: "worker", Demand: 1, Requested: 4}
+	batch := &demand.Task{Name: "batch", Demand: 2, Requested: 2}
+	tasks := &demand.Tasks{Tasks: []*demand.Task{web, worker, batch}}
+
+	if err := e.StopStartTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Scale down first to free up resources, and leave alone anything that doesn't need to change
+	if len(m.updates) != 2 || m.updates[0] != "worker" || m.updates[1] != "web" {
+		t.Fatalf("Expected to update worker then web but updated %v", m.updates)
+	}
+
+	if m.services["web"].DesiredCount != 5 || m.services["worker"].DesiredCount != 1 {
+		t.Fatalf("Desired counts weren't updated")
+	}
+
+	if web.Requested != 5 || worker.Requested != 1 {
+		t.Fatalf("Expected requested to match demand but have %d and %d", web.Requested, worker.Requested)
+	}
+
+	m.failing = true
+	web.Demand = 6
+	if err := e.StopStartTasks(tasks); err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if web.Requested != 5 {
+		t.Fatalf("Requested shouldn't change when the update fails")
+	}
+}
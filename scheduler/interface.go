@@ -19,3 +19,9 @@ type Scheduler interface {
 	// Cleanup is called to give the scheduler a chance to clean up
 	Cleanup() error
 }
+
+// CapacityDiscoverer is implemented by schedulers that can find out how much CPU and memory there is
+// for running tasks, so it doesn't have to be configured
+type CapacityDiscoverer interface {
+	DiscoverCapacity() (demand.Resources, error)
+}
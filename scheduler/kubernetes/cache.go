@@ -0,0 +1,240 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/1.5/pkg/api"
+	"k8s.io/client-go/1.5/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/1.5/pkg/watch"
+)
+
+const (
+	// We list all the deployments again this often, in case we missed any events
+	constResyncPeriod = 5 * time.Minute
+
+	// Delays between attempts if we can't list or watch deployments
+	constRetryMin = 250 * time.Millisecond
+	constRetryMax = 30 * time.Second
+)
+
+var errCacheStopped = errors.New("Deployment cache stopped")
+
+// deploymentClient is the part of the Deployments API that the cache uses, so that it can be faked in tests
+type deploymentClient interface {
+	List(opts api.ListOptions) (*v1beta1.DeploymentList, error)
+	Watch(opts api.ListOptions) (watch.Interface, error)
+}
+
+// deploymentCache keeps a copy of the deployments we manage up to date by watching the Deployments API,
+// rather than getting each deployment every time we want to count its pods.
+type deploymentCache struct {
+	client      deploymentClient
+	resync      time.Duration
+	managed     map[string]bool
+	deployments map[string]v1beta1.Deployment
+	synced      bool
+	relist      chan struct{}
+	stop        chan struct{}
+	stopOnce    sync.Once
+	sync.RWMutex
+}
+
+func newDeploymentCache(client deploymentClient, resync time.Duration) *deploymentCache {
+	return &deploymentCache{
+		client:      client,
+		resync:      resync,
+		managed:     make(map[string]bool),
+		deployments: make(map[string]v1beta1.Deployment),
+		relist:      make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+	}
+}
+
+// manage adds a deployment to the cache. If we haven't got it yet the cache isn't synced until we list again.
+func (c *deploymentCache) manage(name string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.managed[name] = true
+	if _, ok := c.deployments[name]; !ok && c.synced {
+		c.synced = false
+		select {
+		case c.relist <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// get returns the cached deployment. We can only trust that it doesn't exist if the cache is synced.
+func (c *deploymentCache) get(name string) (d v1beta1.Deployment, ok bool, synced bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	d, ok = c.deployments[name]
+	return d, ok, c.synced
+}
+
+// run lists and watches deployments until the cache is stopped
+func (c *deploymentCache) run() {
+	delay := constRetryMin
+
+	for {
+		resourceVersion, err := c.list()
+		if err == nil {
+			err = c.watch(resourceVersion)
+		}
+
+		if err == errCacheStopped {
+			return
+		}
+
+		if err == nil {
+			// Time to list again, so start backing off from the beginning next time something goes wrong
+			delay = constRetryMin
+		}
+
+		if err != nil {
+			log.Errorf("Error watching deployments, retrying in %v: %v", delay, err)
+			select {
+			case <-c.stop:
+				return
+			case <-time.After(delay):
+			}
+
+			delay *= 2
+			if delay > constRetryMax {
+				delay = constRetryMax
+			}
+		}
+	}
+}
+
+// list replaces the cached deployments, and returns the resource version to start watching from
+func (c *deploymentCache) list() (string, error) {
+	list, err := c.client.List(api.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.deployments = make(map[string]v1beta1.Deployment, len(c.managed))
+	for _, d := range list.Items {
+		if c.managed[d.Name] {
+			c.deployments[d.Name] = d
+		}
+	}
+
+	c.synced = true
+	log.Debugf("Listed %d deployments at version %s", len(c.deployments), list.ResourceVersion)
+	return list.ResourceVersion, nil
+}
+
+// watch applies changes to the cache until it's time to list again. If the API server closes the watch
+// we carry on from the last version we saw. If it closes without sending anything we wait before trying
+// again, so we don't keep hitting the API server.
+func (c *deploymentCache) watch(resourceVersion string) error {
+	resync := time.NewTimer(c.resync)
+	defer resync.Stop()
+
+	delay := constRetryMin
+
+	for {
+		w, err := c.client.Watch(api.ListOptions{Watch: true, ResourceVersion: resourceVersion})
+		if err != nil {
+			return err
+		}
+
+		latest, err := c.handleEvents(w, resourceVersion, resync.C)
+		w.Stop()
+		if err != nil || latest == "" {
+			return err
+		}
+
+		if latest != resourceVersion {
+			delay = constRetryMin
+			resourceVersion = latest
+			log.Debugf("Deployment watch closed, reconnecting at version %s", resourceVersion)
+			continue
+		}
+
+		log.Debugf("Deployment watch closed without any events, reconnecting in %v", delay)
+		select {
+		case <-c.stop:
+			return errCacheStopped
+		case <-resync.C:
+			return nil
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > constRetryMax {
+			delay = constRetryMax
+		}
+	}
+}
+
+// handleEvents returns the last resource version when the watch closes, or an empty version if
+// we should list again. An error event means we need to list again too, but after backing off in case
+// the API server keeps sending them.
+func (c *deploymentCache) handleEvents(w watch.Interface, resourceVersion string, resync <-chan time.Time) (string, error) {
+	for {
+		select {
+		case <-c.stop:
+			return "", errCacheStopped
+
+		case <-resync:
+			log.Debug("Resyncing deployments")
+			return "", nil
+
+		case <-c.relist:
+			return "", nil
+
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion, nil
+			}
+
+			if event.Type == watch.Error {
+				// Most likely the version we're watching from is too old
+				return "", fmt.Errorf("Error event watching deployments: %v", event.Object)
+			}
+
+			d, ok := event.Object.(*v1beta1.Deployment)
+			if !ok {
+				log.Errorf("Unexpected object %T watching deployments", event.Object)
+				continue
+			}
+
+			resourceVersion = d.ResourceVersion
+			c.update(event.Type, d)
+		}
+	}
+}
+
+func (c *deploymentCache) update(eventType watch.EventType, d *v1beta1.Deployment) {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.managed[d.Name] {
+		return
+	}
+
+	switch eventType {
+	case watch.Added, watch.Modified:
+		c.deployments[d.Name] = *d
+	case watch.Deleted:
+		delete(c.deployments, d.Name)
+	}
+}
+
+// stopCache stops watching. It's safe to call more than once.
+func (c *deploymentCache) stopCache() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
@@ -0,0 +1,255 @@
+package kubernetes
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/1.5/pkg/api"
+	"k8s.io/client-go/1.5/pkg/api/unversioned"
+	"k8s.io/client-go/1.5/pkg/api/v1"
+	"k8s.io/client-go/1.5/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/1.5/pkg/watch"
+
+	"github.com/microscaling/microscaling/demand"
+)
+
+// fakeDeployments is a stand-in for the Deployments API. Each watch is sent on the watchers channel so
+// that the test can send events on it.
+type fakeDeployments struct {
+	sync.Mutex
+	items       []v1beta1.Deployment
+	listErr     error
+	lists       int
+	watchedFrom []string
+	watchers    chan *watch.FakeWatcher
+	alwaysError bool
+}
+
+func newFakeDeployments(items ...v1beta1.Deployment) *fakeDeployments {
+	return &fakeDeployments{
+		items:    items,
+		watchers: make(chan *watch.FakeWatcher, 10),
+	}
+}
+
+func (f *fakeDeployments) List(opts api.ListOptions) (*v1beta1.DeploymentList, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.lists++
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+
+	return &v1beta1.DeploymentList{
+		ListMeta: unversioned.ListMeta{ResourceVersion: "10"},
+		Items:    f.items,
+	}, nil
+}
+
+func (f *fakeDeployments) Watch(opts api.ListOptions) (watch.Interface, error) {
+	f.Lock()
+	f.watchedFrom = append(f.watchedFrom, opts.ResourceVersion)
+	f.Unlock()
+
+	w := watch.NewFakeWithChanSize(10)
+	if f.alwaysError {
+		w.Error(&unversioned.Status{Code: 500, Reason: unversioned.StatusReasonInternalError})
+		return w, nil
+	}
+
+	f.watchers <- w
+	return w, nil
+}
+
+func (f *fakeDeployments) counts() (lists int, watchedFrom []string) {
+	f.Lock()
+	defer f.Unlock()
+	return f.lists, append([]string(nil), f.watchedFrom...)
+}
+
+func (f *fakeDeployments) nextWatcher(t *testing.T) *watch.FakeWatcher {
+	select {
+	case w := <-f.watchers:
+		return w
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for a watch")
+	}
+	return nil
+}
+
+func newDeployment(name string, version string, available int32) *v1beta1.Deployment {
+	return &v1beta1.Deployment{
+		ObjectMeta: v1.ObjectMeta{Name: name, ResourceVersion: version},
+		Status:     v1beta1.DeploymentStatus{AvailableReplicas: available},
+	}
+}
+
+// waitFor checks the cache until the condition is true
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func available(c *deploymentCache, name string) (int32, bool) {
+	d, ok, synced := c.get(name)
+	return d.Status.AvailableReplicas, ok && synced
+}
+
+func TestDeploymentCache(t *testing.T) {
+	f := newFakeDeployments(*newDeployment("web", "5", 2), *newDeployment("other", "6", 1))
+	c := newDeploymentCache(f, time.Hour)
+	c.manage("web")
+	c.manage("worker")
+	go c.run()
+	defer c.stopCache()
+
+	w := f.nextWatcher(t)
+	if n, ok := available(c, "web"); !ok || n != 2 {
+		t.Fatalf("Expected 2 web pods from the list but have %d %v", n, ok)
+	}
+
+	if _, ok, synced := c.get("other"); ok || !synced {
+		t.Fatalf("Shouldn't cache deployments we don't manage")
+	}
+
+	// Changes come from the watch
+	w.Modify(newDeployment("web", "11", 3))
+	w.Add(newDeployment("worker", "12", 1))
+	w.Add(newDeployment("other", "13", 4))
+	waitFor(t, "updates", func() bool {
+		web, _ := available(c, "web")
+		worker, _ := available(c, "worker")
+		return web == 3 && worker == 1
+	})
+
+	w.Delete(newDeployment("worker", "14", 1))
+	waitFor(t, "delete", func() bool {
+		_, ok, _ := c.get("worker")
+		return !ok
+	})
+
+	// The API server closed the watch, so we carry on without listing again
+	w.Stop()
+	w = f.nextWatcher(t)
+	lists, watchedFrom := f.counts()
+	if lists != 1 || len(watchedFrom) != 2 || watchedFrom[0] != "10" || watchedFrom[1] != "14" {
+		t.Fatalf("Expected to reconnect from the last version but listed %d, watched from %v", lists, watchedFrom)
+	}
+
+	// If the watch closes straight away we wait before trying again
+	closed := time.Now()
+	w.Stop()
+	w = f.nextWatcher(t)
+	if waited := time.Since(closed); waited < constRetryMin {
+		t.Fatalf("Expected to wait before watching again but only waited %v", waited)
+	}
+
+	// An error means we need to list again
+	w.Error(&unversioned.Status{Code: 410, Reason: unversioned.StatusReasonGone})
+	f.nextWatcher(t)
+	lists, watchedFrom = f.counts()
+	if lists != 2 || watchedFrom[2] != "14" || watchedFrom[3] != "10" {
+		t.Fatalf("Expected to list again after an error but listed %d, watched from %v", lists, watchedFrom)
+	}
+}
+
+func TestDeploymentCacheErrorEvents(t *testing.T) {
+	f := newFakeDeployments(*newDeployment("web", "5", 2))
+	f.alwaysError = true
+
+	c := newDeploymentCache(f, time.Hour)
+	c.manage("web")
+	go c.run()
+
+	// We back off for 250ms, then 500ms, so should only have listed 3 times rather than hammering the API server
+	time.Sleep(time.Second)
+	c.stopCache()
+
+	if lists, _ := f.counts(); lists < 2 || lists > 3 {
+		t.Fatalf("Expected to back off between lists after error events but listed %d times", lists)
+	}
+}
+
+func TestDeploymentCacheResync(t *testing.T) {
+	f := newFakeDeployments(*newDeployment("web", "5", 2))
+	f.listErr = errors.New("connection refused")
+
+	c := newDeploymentCache(f, 50*time.Millisecond)
+	c.manage("web")
+	go c.run()
+	defer c.stopCache()
+
+	// Not synced until we can list
+	waitFor(t, "list attempt", func() bool {
+		lists, _ := f.counts()
+		return lists > 0
+	})
+
+	if _, _, synced := c.get("web"); synced {
+		t.Fatalf("Shouldn't be synced if we couldn't list")
+	}
+
+	f.Lock()
+	f.listErr = nil
+	f.Unlock()
+
+	f.nextWatcher(t)
+	if n, ok := available(c, "web"); !ok || n != 2 {
+		t.Fatalf("Expected 2 web pods after retrying but have %d %v", n, ok)
+	}
+
+	// List again after the resync period
+	f.nextWatcher(t)
+	if lists, _ := f.counts(); lists < 3 {
+		t.Fatalf("Expected to list again after resync but listed %d times", lists)
+	}
+
+	// A new deployment needs a new list
+	c.manage("worker")
+	if _, _, synced := c.get("worker"); synced {
+		t.Fatalf("Shouldn't be synced for a deployment we haven't listed")
+	}
+}
+
+func TestKubernetesCountFromCache(t *testing.T) {
+	f := newFakeDeployments(*newDeployment("web", "5", 2), *newDeployment("worker", "6", 1))
+	c := newDeploymentCache(f, time.Hour)
+	c.manage("web")
+	c.manage("worker")
+	c.manage("missing")
+	go c.run()
+	defer c.stopCache()
+
+	w := f.nextWatcher(t)
+	k := &KubernetesScheduler{cache: c}
+
+	web := &demand.Task{Name: "web"}
+	worker := &demand.Task{Name: "worker"}
+	tasks := &demand.Tasks{Tasks: []*demand.Task{web, worker}}
+
+	if err := k.CountAllTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if web.Running != 2 || worker.Running != 1 {
+		t.Fatalf("Expected 2 and 1 running but have %d and %d", web.Running, worker.Running)
+	}
+
+	w.Modify(newDeployment("web", "11", 4))
+	waitFor(t, "update", func() bool {
+		k.CountAllTasks(tasks)
+		return web.Running == 4
+	})
+
+	if _, err := k.countTasks(&demand.Task{Name: "missing"}); err == nil {
+		t.Fatalf("Expected an error for a missing deployment")
+	}
+}
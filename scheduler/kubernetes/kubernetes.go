@@ -2,42 +2,33 @@
 package kubernetes
 
 import (
-	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/op/go-logging"
 
 	"k8s.io/client-go/1.5/kubernetes"
 	"k8s.io/client-go/1.5/pkg/api"
+	"k8s.io/client-go/1.5/pkg/api/errors"
+	"k8s.io/client-go/1.5/pkg/api/v1"
 
 	"github.com/microscaling/microscaling/demand"
 	"github.com/microscaling/microscaling/scheduler"
 	"github.com/microscaling/microscaling/utils"
 )
 
-var (
-	log = logging.MustGetLogger("mssscheduler")
-	pt  = api.StrategicMergePatchType
-)
+var log = logging.MustGetLogger("mssscheduler")
 
-// KubernetesScheduler holds the Kubernetes clientset and a Backoff struct for each task.
+// KubernetesScheduler holds the Kubernetes clientset, a cache of the deployments we're scaling and a
+// Backoff struct for each task.
 type KubernetesScheduler struct {
 	clientset    *kubernetes.Clientset
 	namespace    string
+	cache        *deploymentCache
 	demandUpdate chan struct{}
 	backoff      *utils.Backoff
 }
 
-// deploymentSpec updates the Deployments API
-type deploymentSpec struct {
-	Spec deployment `json:"spec"`
-}
-
-// deployment sends the number of pods to run
-type deployment struct {
-	Replicas int32 `json:"replicas"`
-}
-
 // NewScheduler returns a pointer to the scheduler. Creates k8s clientset from the provided kube
 // config or when running as a pod uses the in cluster config.
 func NewScheduler(kubeConfig string, namespace string, demandUpdate chan struct{}) *KubernetesScheduler {
@@ -47,9 +38,13 @@ func NewScheduler(kubeConfig string, namespace string, demandUpdate chan struct{
 		return nil
 	}
 
+	cache := newDeploymentCache(clientset.Extensions().Deployments(namespace), constResyncPeriod)
+	go cache.run()
+
 	return &KubernetesScheduler{
 		clientset:    clientset,
 		namespace:    namespace,
+		cache:        cache,
 		demandUpdate: demandUpdate,
 		backoff: &utils.Backoff{
 			Min:    250 * time.Millisecond,
@@ -59,16 +54,81 @@ func NewScheduler(kubeConfig string, namespace string, demandUpdate chan struct{
 	}
 }
 
-// compile-time assert that we implement the right interface
+// compile-time assert that we implement the right interfaces
 var _ scheduler.Scheduler = (*KubernetesScheduler)(nil)
+var _ scheduler.CapacityDiscoverer = (*KubernetesScheduler)(nil)
 
-// InitScheduler initializes the scheduler.
+// InitScheduler initializes the scheduler. The task scales a deployment unless it says which kind of workload
+// to scale. If resources haven't been configured for the task we take them from the requests in the
+// workload's pod template.
 func (k *KubernetesScheduler) InitScheduler(task *demand.Task) (err error) {
 	log.Infof("Kubernetes initializing task %s", task.Name)
+
+	kind, wk, err := kindOf(task)
+	if err != nil {
+		return err
+	}
+
+	if kind == constDefaultKind {
+		k.cache.manage(task.Name)
+	}
+
+	if task.Resources.IsZero() {
+		w, err := k.getWorkload(wk, task.Name)
+		if err != nil {
+			// We can still scale the task, we just can't account for its resources
+			log.Errorf("Error getting %s %s to read resource requests: %v", kind, task.Name, err)
+			return nil
+		}
+
+		task.Resources = podRequests(w.Spec.Template.Spec)
+		log.Debugf("%s %s requests CPU %dm, memory %d per pod", kind, task.Name, task.Resources.CPU, task.Resources.Memory)
+	}
+
 	return err
 }
 
-// StopStartTasks by calling the Kubernetes Deployments API.
+// podRequests adds up the resources requested by all the containers in a pod
+func podRequests(podSpec v1.PodSpec) (r demand.Resources) {
+	for _, c := range podSpec.Containers {
+		if cpu, ok := c.Resources.Requests[v1.ResourceCPU]; ok {
+			r.CPU += cpu.MilliValue()
+		}
+
+		if memory, ok := c.Resources.Requests[v1.ResourceMemory]; ok {
+			r.Memory += memory.Value()
+		}
+	}
+
+	return r
+}
+
+// DiscoverCapacity adds up the CPU and memory that can be allocated to pods on all the schedulable nodes
+func (k *KubernetesScheduler) DiscoverCapacity() (r demand.Resources, err error) {
+	nodes, err := k.clientset.Core().Nodes().List(api.ListOptions{})
+	if err != nil {
+		log.Errorf("Error listing nodes: %v", err)
+		return r, err
+	}
+
+	for _, n := range nodes.Items {
+		if n.Spec.Unschedulable {
+			continue
+		}
+
+		if cpu, ok := n.Status.Allocatable[v1.ResourceCPU]; ok {
+			r.CPU += cpu.MilliValue()
+		}
+
+		if memory, ok := n.Status.Allocatable[v1.ResourceMemory]; ok {
+			r.Memory += memory.Value()
+		}
+	}
+
+	return r, err
+}
+
+// StopStartTasks by setting the replicas through the scale subresource of each workload.
 func (k *KubernetesScheduler) StopStartTasks(tasks *demand.Tasks) error {
 	// Create tasks if there aren't enough of them, and stop them if there are too many
 	var tooMany []*demand.Task
@@ -101,7 +161,7 @@ func (k *KubernetesScheduler) StopStartTasks(tasks *demand.Tasks) error {
 	for _, t := range tasksToScale {
 		log.Debugf("Scaling task %s to %d", t.Name, t.Demand)
 
-		running, err := k.countTasks(t.Name)
+		running, err := k.countTasks(t)
 		if err != nil {
 			log.Errorf("Error getting task count for %s: %v", t.Name, err)
 			return err
@@ -112,6 +172,12 @@ func (k *KubernetesScheduler) StopStartTasks(tasks *demand.Tasks) error {
 			k.backoff.Reset()
 
 			err := k.stopStartTask(t)
+			if errors.IsConflict(err) {
+				// Someone else changed the workload since we read its scale, so try again after a backoff
+				log.Debugf("Backing off %s after a conflict: %v", t.Name, err)
+				return k.backoff.Backoff(k.demandUpdate)
+			}
+
 			if err != nil {
 				log.Errorf("Error scaling %s: %v ", t.Name, err)
 				return err
@@ -131,44 +197,36 @@ func (k *KubernetesScheduler) StopStartTasks(tasks *demand.Tasks) error {
 	return err
 }
 
-// CountAllTasks tells us how many pods of each deployment are currently running.
+// CountAllTasks tells us how many pods of each workload are currently running.
 func (k *KubernetesScheduler) CountAllTasks(running *demand.Tasks) (err error) {
 	running.Lock()
 	defer running.Unlock()
 
-	// Set running counts. Defaults to 0 if the deployment does not exist.
+	// Set running counts. Defaults to 0 if the workload does not exist.
 	tasks := running.Tasks
 	for _, t := range tasks {
-		running, err := k.countTasks(t.Name)
+		running, err := k.countTasks(t)
 		if err != nil {
-			log.Errorf("Error getting deployment %s: %v", t.Name, err)
+			log.Errorf("Error getting workload %s: %v", t.Name, err)
 			return err
 		}
 
 		t.Running = running
-		log.Debugf("Deployment %s: requested %d, running %d", t.Name, t.Requested, running)
+		log.Debugf("Workload %s: requested %d, running %d", t.Name, t.Requested, running)
 	}
 
 	return err
 }
 
-// stopStartTask patches the deployment to set the desired number of pods
+// stopStartTask sets the desired number of pods for the task's workload
 func (k *KubernetesScheduler) stopStartTask(task *demand.Task) (err error) {
-	deployment := deploymentSpec{
-		deployment{
-			Replicas: int32(task.Demand),
-		},
-	}
-
-	bytes, err := json.Marshal(deployment)
+	_, wk, err := kindOf(task)
 	if err != nil {
-		log.Errorf("Error marshaling deployment json for %s: %v", task.Name, err)
 		return err
 	}
 
-	_, err = k.clientset.Extensions().Deployments(k.namespace).Patch(task.Name, pt, bytes)
+	err = k.scaleWorkload(wk, task.Name, int32(task.Demand))
 	if err != nil {
-		log.Errorf("Error patching deployment %s: %v", task.Name, err)
 		return err
 	}
 
@@ -177,20 +235,39 @@ func (k *KubernetesScheduler) stopStartTask(task *demand.Task) (err error) {
 	return err
 }
 
-// countTasks counts how many running pods exist for the deployment
-func (k *KubernetesScheduler) countTasks(taskName string) (count int, err error) {
-	d, err := k.clientset.Extensions().Deployments(k.namespace).Get(taskName)
+// countTasks counts how many running pods exist for the task's workload. We get deployments from the cache,
+// unless the cache hasn't caught up with them yet.
+func (k *KubernetesScheduler) countTasks(task *demand.Task) (count int, err error) {
+	kind, wk, err := kindOf(task)
+	if err != nil {
+		return count, err
+	}
+
+	if kind == constDefaultKind {
+		cached, ok, synced := k.cache.get(task.Name)
+		if synced {
+			if !ok {
+				return count, fmt.Errorf("Deployment %s not found", task.Name)
+			}
+
+			count = int(cached.Status.AvailableReplicas)
+			return count, err
+		}
+	}
+
+	w, err := k.getWorkload(wk, task.Name)
 	if err != nil {
-		log.Errorf("Error getting deployment %s: %v", taskName, err)
+		log.Errorf("Error getting %s %s: %v", kind, task.Name, err)
 		return count, err
 	}
 
-	count = int(d.Status.AvailableReplicas)
+	count = int(wk.running(w.Status))
 	return count, err
 }
 
 // Cleanup gives the scheduler an opportunity to stop anything that needs to be stopped
 func (k *KubernetesScheduler) Cleanup() error {
 	k.backoff.Stop()
+	k.cache.stopCache()
 	return nil
 }
@@ -5,6 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"k8s.io/client-go/1.5/pkg/api/resource"
+	"k8s.io/client-go/1.5/pkg/api/v1"
+
 	"github.com/microscaling/microscaling/demand"
 )
 
@@ -14,6 +17,7 @@ func TestKubernetesInitScheduler(t *testing.T) {
 	demandUpdate := make(chan struct{}, 1)
 
 	k := NewScheduler(kubeConfig, namespace, demandUpdate)
+	defer k.Cleanup()
 
 	task := demand.Task{
 		Name:   "consumer",
@@ -38,3 +42,34 @@ func TestKubernetesInitScheduler(t *testing.T) {
 		t.Errorf("Expected max backoff to be 5 secs but was %d", k.backoff.Max)
 	}
 }
+
+func TestPodRequests(t *testing.T) {
+	podSpec := v1.PodSpec{
+		Containers: []v1.Container{
+			{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("250m"),
+						v1.ResourceMemory: resource.MustParse("64Mi"),
+					},
+				},
+			},
+			{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU: resource.MustParse("1"),
+					},
+				},
+			},
+		},
+	}
+
+	r := podRequests(podSpec)
+	if r.CPU != 1250 {
+		t.Errorf("Expected CPU to be 1250m but was %dm", r.CPU)
+	}
+
+	if r.Memory != 64*1024*1024 {
+		t.Errorf("Expected memory to be 64Mi but was %d", r.Memory)
+	}
+}
@@ -0,0 +1,162 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"k8s.io/client-go/1.5/pkg/api/v1"
+	"k8s.io/client-go/1.5/rest"
+
+	"github.com/microscaling/microscaling/demand"
+)
+
+// Tasks scale deployments unless they say otherwise
+const constDefaultKind = "Deployment"
+
+// workloadKind says where to find a kind of workload in the Kubernetes API, and which status field
+// counts the pods that are ready to do work.
+type workloadKind struct {
+	apiPath         string
+	resource        string
+	scaleAPIVersion string
+	running         func(status workloadStatus) int32
+}
+
+var workloadKinds = map[string]workloadKind{
+	"Deployment": {
+		apiPath:         "/apis/extensions/v1beta1",
+		resource:        "deployments",
+		scaleAPIVersion: "extensions/v1beta1",
+		running:         availableReplicas,
+	},
+	"ReplicaSet": {
+		apiPath:         "/apis/extensions/v1beta1",
+		resource:        "replicasets",
+		scaleAPIVersion: "extensions/v1beta1",
+		running:         readyReplicas,
+	},
+	"ReplicationController": {
+		apiPath:         "/api/v1",
+		resource:        "replicationcontrollers",
+		scaleAPIVersion: "autoscaling/v1",
+		running:         readyReplicas,
+	},
+	"StatefulSet": {
+		apiPath:         "/apis/apps/v1beta1",
+		resource:        "statefulsets",
+		scaleAPIVersion: "apps/v1beta1",
+		running:         readyReplicas,
+	},
+}
+
+// Kinds returns the kinds of workload we can scale, so config can be checked before we start
+func Kinds() []string {
+	kinds := make([]string, 0, len(workloadKinds))
+	for k := range workloadKinds {
+		kinds = append(kinds, k)
+	}
+
+	sort.Strings(kinds)
+	return kinds
+}
+
+func availableReplicas(status workloadStatus) int32 {
+	return status.AvailableReplicas
+}
+
+func readyReplicas(status workloadStatus) int32 {
+	return status.ReadyReplicas
+}
+
+// workload has the parts of any of these kinds that we need
+type workload struct {
+	Spec struct {
+		Template struct {
+			Spec v1.PodSpec `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status workloadStatus `json:"status"`
+}
+
+// workloadStatus has the replica counts for any of these kinds. Not every kind has every count.
+type workloadStatus struct {
+	Replicas          int32 `json:"replicas"`
+	ReadyReplicas     int32 `json:"readyReplicas"`
+	AvailableReplicas int32 `json:"availableReplicas"`
+}
+
+// scale is the scale subresource. It's the same for every kind apart from the API version, and we send
+// the metadata back as we got it so the update fails if someone else changed it first.
+type scale struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Metadata   json.RawMessage `json:"metadata"`
+	Spec       scaleSpec       `json:"spec"`
+}
+
+type scaleSpec struct {
+	Replicas int32 `json:"replicas"`
+}
+
+// kindOf returns the kind of workload the task scales
+func kindOf(task *demand.Task) (string, workloadKind, error) {
+	kind := task.Kind
+	if kind == "" {
+		kind = constDefaultKind
+	}
+
+	wk, ok := workloadKinds[kind]
+	if !ok {
+		return kind, wk, fmt.Errorf("Can't scale %s as kind %s isn't supported", task.Name, kind)
+	}
+
+	return kind, wk, nil
+}
+
+// request builds a request for the workload, or one of its subresources
+func (k *KubernetesScheduler) request(verb string, wk workloadKind, name string, subresource ...string) *rest.Request {
+	segments := append([]string{wk.apiPath, "namespaces", k.namespace, wk.resource, name}, subresource...)
+	return k.clientset.Core().GetRESTClient().Verb(verb).AbsPath(segments...)
+}
+
+// getWorkload gets the pod template and status of the workload
+func (k *KubernetesScheduler) getWorkload(wk workloadKind, name string) (w workload, err error) {
+	body, err := k.request("GET", wk, name).DoRaw()
+	if err != nil {
+		return w, err
+	}
+
+	err = json.Unmarshal(body, &w)
+	if err != nil {
+		return w, fmt.Errorf("Error %v unmarshalling %s %s", err, wk.resource, name)
+	}
+
+	return w, err
+}
+
+// scaleWorkload sets the number of replicas through the scale subresource
+func (k *KubernetesScheduler) scaleWorkload(wk workloadKind, name string, replicas int32) error {
+	body, err := k.request("GET", wk, name, "scale").DoRaw()
+	if err != nil {
+		return err
+	}
+
+	var s scale
+	err = json.Unmarshal(body, &s)
+	if err != nil {
+		return fmt.Errorf("Error %v unmarshalling scale for %s %s", err, wk.resource, name)
+	}
+
+	s.APIVersion = wk.scaleAPIVersion
+	s.Kind = "Scale"
+	s.Spec.Replicas = replicas
+
+	body, err = json.Marshal(&s)
+	if err != nil {
+		return err
+	}
+
+	_, err = k.request("PUT", wk, name, "scale").SetHeader("Content-Type", "application/json").Body(body).DoRaw()
+	return err
+}
@@ -0,0 +1,189 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/1.5/kubernetes"
+	"k8s.io/client-go/1.5/rest"
+
+	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/utils"
+)
+
+// fakeKube is a stand-in for the workload and scale APIs
+type fakeKube struct {
+	t        *testing.T
+	conflict bool
+	scaled   map[string]scale
+}
+
+var fakeWorkloads = map[string]string{
+	"/apis/extensions/v1beta1/namespaces/default/deployments/web": `{"status": {"replicas": 3, "readyReplicas": 3, "availableReplicas": 2}}`,
+	"/api/v1/namespaces/default/replicationcontrollers/legacy":    `{"status": {"replicas": 2, "readyReplicas": 1}}`,
+	"/apis/apps/v1beta1/namespaces/default/statefulsets/db": `{
+		"spec": {"template": {"spec": {"containers": [{"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}}]}}},
+		"status": {"replicas": 3, "readyReplicas": 2}
+	}`,
+}
+
+func (f *fakeKube) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if body, ok := fakeWorkloads[r.URL.Path]; ok && r.Method == "GET" {
+		w.Write([]byte(body))
+		return
+	}
+
+	path := r.URL.Path[:len(r.URL.Path)-len("/scale")]
+	if _, ok := fakeWorkloads[path]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404}`))
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		w.Write([]byte(`{"kind": "Scale", "metadata": {"name": "x", "namespace": "default", "resourceVersion": "7"}, "spec": {"replicas": 3}}`))
+
+	case "PUT":
+		if f.conflict {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "Conflict", "code": 409}`))
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		var s scale
+		if err := json.Unmarshal(body, &s); err != nil {
+			f.t.Errorf("Bad scale %s: %v", string(body), err)
+		}
+
+		f.scaled[path] = s
+		w.Write(body)
+	}
+}
+
+func newTestWorkloadScheduler(t *testing.T, f *fakeKube) (*KubernetesScheduler, func()) {
+	server := httptest.NewServer(f)
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create clientset: %v", err)
+	}
+
+	// The cache never syncs, so deployments come from the API too
+	k := &KubernetesScheduler{
+		clientset:    clientset,
+		namespace:    "default",
+		cache:        newDeploymentCache(newFakeDeployments(), time.Hour),
+		demandUpdate: make(chan struct{}, 1),
+		backoff: &utils.Backoff{
+			Min:    250 * time.Millisecond,
+			Max:    5 * time.Second,
+			Factor: 2,
+		},
+	}
+
+	return k, func() {
+		k.Cleanup()
+		server.Close()
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		kind     string
+		expected string
+		pass     bool
+	}{
+		{kind: "", expected: "Deployment", pass: true},
+		{kind: "StatefulSet", expected: "StatefulSet", pass: true},
+		{kind: "ReplicationController", expected: "ReplicationController", pass: true},
+		{kind: "DaemonSet", pass: false},
+	}
+
+	for _, test := range tests {
+		kind, wk, err := kindOf(&demand.Task{Name: "app", Kind: test.kind})
+		if (err == nil) != test.pass {
+			t.Errorf("Kind %s: unexpected result %v", test.kind, err)
+			continue
+		}
+
+		if test.pass && (kind != test.expected || wk.resource == "") {
+			t.Errorf("Kind %s: expected %s but got %s %+v", test.kind, test.expected, kind, wk)
+		}
+	}
+}
+
+func TestKubernetesWorkloads(t *testing.T) {
+	f := &fakeKube{t: t, scaled: make(map[string]scale)}
+	k, cleanup := newTestWorkloadScheduler(t, f)
+	defer cleanup()
+
+	web := &demand.Task{Name: "web"}
+	legacy := &demand.Task{Name: "legacy", Kind: "ReplicationController"}
+	db := &demand.Task{Name: "db", Kind: "StatefulSet"}
+	tasks := &demand.Tasks{Tasks: []*demand.Task{web, legacy, db}}
+
+	for _, task := range tasks.Tasks {
+		if err := k.InitScheduler(task); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if db.Resources.CPU != 500 || db.Resources.Memory != 1024*1024*1024 {
+		t.Fatalf("Expected resources from the stateful set's pod template but got %+v", db.Resources)
+	}
+
+	if err := k.InitScheduler(&demand.Task{Name: "logs", Kind: "DaemonSet"}); err == nil {
+		t.Fatalf("Expected an error for a kind we can't scale")
+	}
+
+	// Deployments count available pods, the others count ready pods
+	if err := k.CountAllTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if web.Running != 2 || legacy.Running != 1 || db.Running != 2 {
+		t.Fatalf("Expected 2, 1 and 2 running but have %d, %d and %d", web.Running, legacy.Running, db.Running)
+	}
+
+	// Someone else changed the stateful set, so we back off
+	db.Requested, db.Demand = 2, 4
+	f.conflict = true
+	if err := k.StopStartTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if db.Requested != 2 || !k.backoff.Waiting() {
+		t.Fatalf("Expected to back off after a conflict")
+	}
+
+	// Try again when the backoff tells us to
+	f.conflict = false
+	<-k.demandUpdate
+	if err := k.StopStartTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	s, ok := f.scaled["/apis/apps/v1beta1/namespaces/default/statefulsets/db"]
+	if !ok || s.Spec.Replicas != 4 || s.APIVersion != "apps/v1beta1" || s.Kind != "Scale" {
+		t.Fatalf("Expected to scale the stateful set to 4 but got %+v", s)
+	}
+
+	var metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	}
+	json.Unmarshal(s.Metadata, &metadata)
+	if metadata.ResourceVersion != "7" {
+		t.Errorf("Expected to send back the resource version we read but sent %s", string(s.Metadata))
+	}
+
+	if db.Requested != 4 || len(f.scaled) != 1 {
+		t.Errorf("Expected only the stateful set to be scaled, requested %d, scaled %v", db.Requested, f.scaled)
+	}
+}
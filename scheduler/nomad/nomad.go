@@ -0,0 +1,366 @@
+// Package nomad provides a scheduler that scales task groups in HashiCorp Nomad jobs, using the Nomad HTTP API.
+package nomad
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+
+	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/scheduler"
+	"github.com/microscaling/microscaling/utils"
+)
+
+var log = logging.MustGetLogger("mssscheduler")
+
+// NomadScheduler holds the Nomad API address, the job and task group for each task, and a Backoff struct
+// for when Nomad is still working on a previous change.
+type NomadScheduler struct {
+	baseNomadURL string
+	token        string
+	groups       map[string]taskGroup // indexed by task name
+	demandUpdate chan struct{}
+	backoff      *utils.Backoff
+	sync.Mutex
+}
+
+// taskGroup identifies the group within a job that we scale for a task
+type taskGroup struct {
+	job   string
+	group string
+}
+
+// Job from the Nomad API.
+type Job struct {
+	ID         string      `json:"ID"`
+	TaskGroups []TaskGroup `json:"TaskGroups"`
+}
+
+// TaskGroup from the Nomad API.
+type TaskGroup struct {
+	Name  string `json:"Name"`
+	Count int    `json:"Count"`
+}
+
+// Allocation from the Nomad API. Each running allocation is an instance of a task group.
+type Allocation struct {
+	ID            string `json:"ID"`
+	TaskGroup     string `json:"TaskGroup"`
+	DesiredStatus string `json:"DesiredStatus"`
+	ClientStatus  string `json:"ClientStatus"`
+}
+
+// Evaluation from the Nomad API. Nomad evaluates a job to work out where to place allocations after it changes.
+type Evaluation struct {
+	ID     string `json:"ID"`
+	Status string `json:"Status"`
+}
+
+// scaleRequest sets the count for a task group
+type scaleRequest struct {
+	Count   int               `json:"Count"`
+	Target  map[string]string `json:"Target"`
+	Message string            `json:"Message"`
+}
+
+var (
+	httpClient = &http.Client{
+		// TODO Make timeout configurable.
+		Timeout: 10 * time.Second,
+	}
+)
+
+// NewScheduler returns a pointer to the scheduler. If Nomad has ACLs enabled, the token comes from NOMAD_TOKEN.
+func NewScheduler(nomadAPI string, demandUpdate chan struct{}) *NomadScheduler {
+	return &NomadScheduler{
+		baseNomadURL: strings.TrimSuffix(nomadAPI, "/") + "/v1/",
+		token:        os.Getenv("NOMAD_TOKEN"),
+		groups:       make(map[string]taskGroup),
+		demandUpdate: demandUpdate,
+		backoff: &utils.Backoff{
+			Min:    250 * time.Millisecond,
+			Max:    5 * time.Second,
+			Factor: 2,
+		},
+	}
+}
+
+// compile-time assert that we implement the right interface
+var _ scheduler.Scheduler = (*NomadScheduler)(nil)
+
+// InitScheduler finds the task group to scale for a task. The task name is the job ID, followed by a slash and
+// the group name if the job has more than one group, e.g. "batch/workers".
+func (n *NomadScheduler) InitScheduler(task *demand.Task) error {
+	log.Infof("Nomad initializing task %s", task.Name)
+
+	tg := taskGroup{job: task.Name}
+	if i := strings.LastIndex(task.Name, "/"); i >= 0 {
+		tg.job = task.Name[:i]
+		tg.group = task.Name[i+1:]
+	}
+
+	var job Job
+	status, err := n.get("job/"+url.PathEscape(tg.job), &job)
+	if err != nil {
+		return fmt.Errorf("Error getting job %s: %v", tg.job, err)
+	}
+
+	if status != http.StatusOK {
+		return fmt.Errorf("Error response code %d from Nomad API getting job %s", status, tg.job)
+	}
+
+	if tg.group == "" {
+		if len(job.TaskGroups) != 1 {
+			return fmt.Errorf("Job %s has %d task groups, so task %s needs to say which to scale, e.g. %s/<group>", tg.job, len(job.TaskGroups), task.Name, tg.job)
+		}
+		tg.group = job.TaskGroups[0].Name
+	}
+
+	found := false
+	for _, g := range job.TaskGroups {
+		found = found || g.Name == tg.group
+	}
+
+	if !found {
+		return fmt.Errorf("Job %s has no task group %s", tg.job, tg.group)
+	}
+
+	n.Lock()
+	n.groups[task.Name] = tg
+	n.Unlock()
+	return nil
+}
+
+func (n *NomadScheduler) taskGroup(name string) (taskGroup, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	tg, ok := n.groups[name]
+	if !ok {
+		return tg, fmt.Errorf("Task %s hasn't been initialized", name)
+	}
+	return tg, nil
+}
+
+// StopStartTasks by setting the count for each task group.
+func (n *NomadScheduler) StopStartTasks(tasks *demand.Tasks) error {
+	// Create tasks if there aren't enough of them, and stop them if there are too many
+	var tooMany []*demand.Task
+	var tooFew []*demand.Task
+
+	// Check we're not already backed off. This could easily happen if we get a demand update
+	// arrive while we are in the midst of a previous backoff.
+	if n.backoff.Waiting() {
+		log.Debug("Backoff timer still running")
+		return nil
+	}
+
+	tasks.Lock()
+	defer tasks.Unlock()
+
+	for _, task := range tasks.Tasks {
+		if task.Demand > task.Requested {
+			// There aren't enough of these allocations yet
+			tooFew = append(tooFew, task)
+		}
+		if task.Demand < task.Requested {
+			// There are too many of these allocations
+			tooMany = append(tooMany, task)
+		}
+	}
+
+	// Concatentate the two lists - scale down first to free up resources
+	tasksToScale := append(tooMany, tooFew...)
+	for _, task := range tasksToScale {
+		tg, err := n.taskGroup(task.Name)
+		if err != nil {
+			return err
+		}
+
+		pending, err := n.pendingEvaluations(tg.job)
+		if err != nil {
+			log.Errorf("Error getting evaluations for job %s: %v", tg.job, err)
+			return err
+		}
+
+		if pending {
+			// Nomad is still working out where to put the last change, so trigger a new scaling
+			// operation by signalling a demandUpdate after a backoff delay
+			log.Debugf("Backing off %s as job %s has pending evaluations", task.Name, tg.job)
+			return n.backoff.Backoff(n.demandUpdate)
+		}
+
+		err = n.scale(tg, task.Demand)
+		if err != nil {
+			log.Errorf("Couldn't scale %s: %v ", task.Name, err)
+			return err
+		}
+
+		// Clear any backoffs on success
+		n.backoff.Reset()
+		task.Requested = task.Demand
+		log.Debugf("Now have %s: %d", task.Name, task.Requested)
+	}
+
+	return nil
+}
+
+// pendingEvaluations returns true if Nomad hasn't finished evaluating the job since it last changed
+func (n *NomadScheduler) pendingEvaluations(job string) (bool, error) {
+	var evals []Evaluation
+	status, err := n.get("job/"+url.PathEscape(job)+"/evaluations", &evals)
+	if err != nil {
+		return false, err
+	}
+
+	if status != http.StatusOK {
+		return false, fmt.Errorf("Error response code %d from Nomad API", status)
+	}
+
+	for _, e := range evals {
+		if e.Status == "pending" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// scale submits a post request to Nomad to set the count for the task group
+// format looks like:
+// POST http://nomad:4646/v1/job/<job>/scale
+//
+//	Request:
+//	{
+//	  "Count": 8,
+//	  "Target": {"Group": "<group>"}
+//	}
+func (n *NomadScheduler) scale(tg taskGroup, count int) error {
+	payload := scaleRequest{
+		Count:   count,
+		Target:  map[string]string{"Group": tg.group},
+		Message: "Scaled by microscaling",
+	}
+
+	b, err := json.Marshal(&payload)
+	if err != nil {
+		return err
+	}
+
+	status, body, err := n.do("POST", "job/"+url.PathEscape(tg.job)+"/scale", b)
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		return fmt.Errorf("Error response code %d from Nomad API: %s", status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// CountAllTasks tells us how many allocations of each task group are currently running.
+func (n *NomadScheduler) CountAllTasks(running *demand.Tasks) error {
+	running.Lock()
+	defer running.Unlock()
+
+	// Running allocations for each group, for each job we've looked at
+	counts := make(map[string]map[string]int)
+
+	for _, task := range running.Tasks {
+		tg, err := n.taskGroup(task.Name)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := counts[tg.job]; !ok {
+			counts[tg.job], err = n.countAllocations(tg.job)
+			if err != nil {
+				log.Errorf("Error getting allocations for job %s: %v", tg.job, err)
+				return err
+			}
+		}
+
+		// Defaults to 0 if the job or group does not exist.
+		task.Running = counts[tg.job][tg.group]
+	}
+
+	return nil
+}
+
+// countAllocations counts the running allocations for each task group in a job. Allocations that are
+// being stopped are still running for a while, so we only count the ones Nomad wants to keep running.
+func (n *NomadScheduler) countAllocations(job string) (map[string]int, error) {
+	var allocs []Allocation
+	status, err := n.get("job/"+url.PathEscape(job)+"/allocations", &allocs)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	if status == http.StatusNotFound {
+		return counts, nil
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("Error response code %d from Nomad API", status)
+	}
+
+	for _, a := range allocs {
+		if a.DesiredStatus == "run" && a.ClientStatus == "running" {
+			counts[a.TaskGroup]++
+		}
+	}
+
+	return counts, nil
+}
+
+// get reads JSON from the Nomad API into v, if the request was successful
+func (n *NomadScheduler) get(path string, v interface{}) (status int, err error) {
+	status, body, err := n.do("GET", path, nil)
+	if err != nil || status != http.StatusOK {
+		return status, err
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return status, fmt.Errorf("Error %v unmarshalling from %s", err, string(body))
+	}
+
+	return status, nil
+}
+
+func (n *NomadScheduler) do(method string, path string, payload []byte) (status int, body []byte, err error) {
+	req, err := http.NewRequest(method, n.baseNomadURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("X-Nomad-Token", n.token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err = ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, body, err
+}
+
+// Cleanup gives the scheduler an opportunity to stop anything that needs to be stopped
+func (n *NomadScheduler) Cleanup() error {
+	n.backoff.Stop()
+	return nil
+}
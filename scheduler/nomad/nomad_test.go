@@ -0,0 +1,198 @@
+package nomad
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/microscaling/microscaling/demand"
+)
+
+// fakeNomad is a stand-in for the parts of the Nomad API that we use
+type fakeNomad struct {
+	t       *testing.T
+	jobs    map[string]Job
+	allocs  map[string][]Allocation
+	pending bool
+	scaled  []scaleRequest
+}
+
+func newFakeNomad(t *testing.T) *fakeNomad {
+	return &fakeNomad{
+		t: t,
+		jobs: map[string]Job{
+			"web":   {ID: "web", TaskGroups: []TaskGroup{{Name: "frontend", Count: 2}}},
+			"batch": {ID: "batch", TaskGroups: []TaskGroup{{Name: "workers", Count: 1}, {Name: "reports", Count: 1}}},
+		},
+		allocs: map[string][]Allocation{
+			"web": {
+				{TaskGroup: "frontend", DesiredStatus: "run", ClientStatus: "running"},
+				{TaskGroup: "frontend", DesiredStatus: "run", ClientStatus: "running"},
+				{TaskGroup: "frontend", DesiredStatus: "stop", ClientStatus: "running"},
+				{TaskGroup: "frontend", DesiredStatus: "run", ClientStatus: "pending"},
+			},
+			"batch": {
+				{TaskGroup: "workers", DesiredStatus: "run", ClientStatus: "running"},
+				{TaskGroup: "reports", DesiredStatus: "run", ClientStatus: "complete"},
+			},
+		},
+	}
+}
+
+func (f *fakeNomad) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Nomad-Token") != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/job/"), "/")
+	job, ok := f.jobs[parts[0]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("job not found"))
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		json.NewEncoder(w).Encode(job)
+
+	case parts[1] == "allocations":
+		json.NewEncoder(w).Encode(f.allocs[job.ID])
+
+	case parts[1] == "evaluations":
+		evals := []Evaluation{{ID: "1", Status: "complete"}}
+		if f.pending {
+			evals = append(evals, Evaluation{ID: "2", Status: "pending"})
+		}
+		json.NewEncoder(w).Encode(evals)
+
+	case parts[1] == "scale" && r.Method == "POST":
+		var req scaleRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.scaled = append(f.scaled, req)
+		w.Write([]byte(`{"EvalID":"3"}`))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestScheduler(f *fakeNomad) (*NomadScheduler, func()) {
+	server := httptest.NewServer(f)
+	os.Setenv("NOMAD_TOKEN", "secret")
+	defer os.Unsetenv("NOMAD_TOKEN")
+
+	n := NewScheduler(server.URL, make(chan struct{}, 1))
+	return n, func() {
+		n.Cleanup()
+		server.Close()
+	}
+}
+
+func TestNomadInitScheduler(t *testing.T) {
+	n, cleanup := newTestScheduler(newFakeNomad(t))
+	defer cleanup()
+
+	tests := []struct {
+		name  string
+		job   string
+		group string
+		pass  bool
+	}{
+		{name: "web", job: "web", group: "frontend", pass: true},
+		{name: "web/frontend", job: "web", group: "frontend", pass: true},
+		{name: "batch/workers", job: "batch", group: "workers", pass: true},
+		{name: "batch", pass: false},         // more than one group to choose from
+		{name: "batch/missing", pass: false}, // no such group
+		{name: "missing", pass: false},       // no such job
+	}
+
+	for _, test := range tests {
+		err := n.InitScheduler(&demand.Task{Name: test.name})
+		if (err == nil) != test.pass {
+			t.Errorf("%s: unexpected result %v", test.name, err)
+			continue
+		}
+
+		if test.pass {
+			tg, _ := n.taskGroup(test.name)
+			if tg.job != test.job || tg.group != test.group {
+				t.Errorf("%s: expected job %s group %s but got %+v", test.name, test.job, test.group, tg)
+			}
+		}
+	}
+}
+
+func TestNomadScheduler(t *testing.T) {
+	f := newFakeNomad(t)
+	n, cleanup := newTestScheduler(f)
+	defer cleanup()
+
+	web := &demand.Task{Name: "web"}
+	workers := &demand.Task{Name: "batch/workers"}
+	reports := &demand.Task{Name: "batch/reports"}
+	tasks := &demand.Tasks{Tasks: []*demand.Task{web, workers, reports}}
+
+	for _, task := range tasks.Tasks {
+		if err := n.InitScheduler(task); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if err := n.CountAllTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if web.Running != 2 || workers.Running != 1 || reports.Running != 0 {
+		t.Fatalf("Expected 2, 1 and 0 running but have %d, %d and %d", web.Running, workers.Running, reports.Running)
+	}
+
+	// Nomad hasn't finished with the last change, so we back off
+	web.Requested, web.Demand = 2, 4
+	workers.Requested, workers.Demand = 1, 0
+	f.pending = true
+	if err := n.StopStartTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(f.scaled) != 0 || web.Requested != 2 || !n.backoff.Waiting() {
+		t.Fatalf("Expected to back off while evaluations are pending")
+	}
+
+	// Try again when the backoff tells us to, scaling down first
+	f.pending = false
+	<-n.demandUpdate
+	if err := n.StopStartTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(f.scaled) != 2 {
+		t.Fatalf("Expected 2 scale requests but got %d", len(f.scaled))
+	}
+
+	if f.scaled[0].Target["Group"] != "workers" || f.scaled[0].Count != 0 {
+		t.Errorf("Expected to scale workers to 0 first but got %+v", f.scaled[0])
+	}
+
+	if f.scaled[1].Target["Group"] != "frontend" || f.scaled[1].Count != 4 {
+		t.Errorf("Expected to scale frontend to 4 but got %+v", f.scaled[1])
+	}
+
+	if web.Requested != 4 || workers.Requested != 0 {
+		t.Errorf("Expected requested to match demand but have %d and %d", web.Requested, workers.Requested)
+	}
+
+	// The job has gone away
+	delete(f.jobs, "web")
+	if err := n.CountAllTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if web.Running != 0 {
+		t.Errorf("Expected nothing running for a missing job but have %d", web.Running)
+	}
+}
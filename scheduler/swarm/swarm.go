@@ -0,0 +1,235 @@
+// Package swarm provides a scheduler that scales Docker services running in swarm mode.
+package swarm
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/op/go-logging"
+
+	"github.com/microscaling/microscaling/demand"
+	"github.com/microscaling/microscaling/scheduler"
+	"github.com/microscaling/microscaling/utils"
+)
+
+var log = logging.MustGetLogger("mssscheduler")
+
+// SwarmScheduler holds the Docker client and a Backoff struct for when services can't be updated.
+type SwarmScheduler struct {
+	client       *docker.Client
+	demandUpdate chan struct{}
+	backoff      *utils.Backoff
+}
+
+// NewScheduler returns a pointer to the scheduler, using the Docker remote API on a swarm manager.
+func NewScheduler(dockerHost string, demandUpdate chan struct{}) *SwarmScheduler {
+	client, err := docker.NewClient(dockerHost)
+	if err != nil {
+		log.Errorf("Error starting Docker client: %v", err)
+		return nil
+	}
+
+	return &SwarmScheduler{
+		client:       client,
+		demandUpdate: demandUpdate,
+		backoff: &utils.Backoff{
+			Min:    250 * time.Millisecond,
+			Max:    5 * time.Second,
+			Factor: 2,
+		},
+	}
+}
+
+// compile-time assert that we implement the right interfaces
+var _ scheduler.Scheduler = (*SwarmScheduler)(nil)
+var _ scheduler.CapacityDiscoverer = (*SwarmScheduler)(nil)
+
+// InitScheduler checks that there's a replicated service for the task. If resources haven't been configured
+// for the task we take them from the reservations in the service's task template.
+func (s *SwarmScheduler) InitScheduler(task *demand.Task) error {
+	log.Infof("Swarm initializing task %s", task.Name)
+
+	service, err := s.client.InspectService(task.Name)
+	if err != nil {
+		return fmt.Errorf("Error getting service %s: %v", task.Name, err)
+	}
+
+	if service.Spec.Mode.Replicated == nil {
+		return fmt.Errorf("Service %s is global, so it can't be scaled", task.Name)
+	}
+
+	r := service.Spec.TaskTemplate.Resources
+	if task.Resources.IsZero() && r != nil && r.Reservations != nil {
+		task.Resources.CPU = r.Reservations.NanoCPUs / 1000000
+		task.Resources.Memory = r.Reservations.MemoryBytes
+		log.Debugf("Service %s reserves CPU %dm, memory %d per task", task.Name, task.Resources.CPU, task.Resources.Memory)
+	}
+
+	return nil
+}
+
+// DiscoverCapacity adds up the CPU and memory on all the nodes that are ready for tasks
+func (s *SwarmScheduler) DiscoverCapacity() (r demand.Resources, err error) {
+	nodes, err := s.client.ListNodes(docker.ListNodesOptions{})
+	if err != nil {
+		log.Errorf("Error listing nodes: %v", err)
+		return r, err
+	}
+
+	for _, n := range nodes {
+		if n.Spec.Availability != swarm.NodeAvailabilityActive || n.Status.State != swarm.NodeStateReady {
+			continue
+		}
+
+		r.CPU += n.Description.Resources.NanoCPUs / 1000000
+		r.Memory += n.Description.Resources.MemoryBytes
+	}
+
+	return r, nil
+}
+
+// StopStartTasks by updating the number of replicas for each service.
+func (s *SwarmScheduler) StopStartTasks(tasks *demand.Tasks) error {
+	// Create tasks if there aren't enough of them, and stop them if there are too many
+	var tooMany []*demand.Task
+	var tooFew []*demand.Task
+	var err error
+
+	// Check we're not already backed off. This could easily happen if we get a demand update
+	// arrive while we are in the midst of a previous backoff.
+	if s.backoff.Waiting() {
+		log.Debug("Backoff timer still running")
+		return nil
+	}
+
+	tasks.Lock()
+	defer tasks.Unlock()
+
+	for _, task := range tasks.Tasks {
+		if task.Demand > task.Requested {
+			// There aren't enough of these containers yet
+			tooFew = append(tooFew, task)
+		}
+		if task.Demand < task.Requested {
+			// There are too many of these containers
+			tooMany = append(tooMany, task)
+		}
+	}
+
+	// Concatentate the two lists - scale down first to free up resources
+	tasksToScale := append(tooMany, tooFew...)
+	for _, task := range tasksToScale {
+		blocked, err := s.stopStartTask(task)
+		if blocked {
+			// The service changed since we read it. Trigger a new scaling operation by signalling a
+			// demandUpdate after a backoff delay
+			err = s.backoff.Backoff(s.demandUpdate)
+			return err
+		}
+
+		if err != nil {
+			log.Errorf("Couldn't scale %s: %v ", task.Name, err)
+			return err
+		}
+
+		// Clear any backoffs on success
+		s.backoff.Reset()
+		log.Debugf("Now have %s: %d", task.Name, task.Requested)
+	}
+
+	return err
+}
+
+// stopStartTask sets the number of replicas for the service. Swarm only accepts the update if the service
+// hasn't changed since we read it, so blocked is true if there was a conflict and we should try again later.
+func (s *SwarmScheduler) stopStartTask(task *demand.Task) (blocked bool, err error) {
+	service, err := s.client.InspectService(task.Name)
+	if err != nil {
+		return false, err
+	}
+
+	if service.Spec.Mode.Replicated == nil {
+		return false, fmt.Errorf("Service %s is global, so it can't be scaled", task.Name)
+	}
+
+	replicas := uint64(task.Demand)
+	service.Spec.Mode.Replicated.Replicas = &replicas
+
+	err = s.client.UpdateService(service.ID, docker.UpdateServiceOptions{
+		ServiceSpec: service.Spec,
+		Version:     service.Version.Index,
+	})
+	if isConflict(err) {
+		log.Debugf("Service %s was updated by someone else", task.Name)
+		return true, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	task.Requested = task.Demand
+	return false, nil
+}
+
+// isConflict tells us if an update failed because the service version was out of date. Older versions
+// of Docker report this as an internal error, so we have to check the message as well.
+func isConflict(err error) bool {
+	e, ok := err.(*docker.Error)
+	if !ok {
+		return false
+	}
+
+	return e.Status == http.StatusConflict || strings.Contains(e.Message, "update out of sequence")
+}
+
+// CountAllTasks tells us how many tasks of each service are currently running.
+func (s *SwarmScheduler) CountAllTasks(running *demand.Tasks) error {
+	running.Lock()
+	defer running.Unlock()
+
+	services, err := s.client.ListServices(docker.ListServicesOptions{})
+	if err != nil {
+		log.Errorf("Error listing services: %v", err)
+		return err
+	}
+
+	serviceNames := make(map[string]string, len(services))
+	for _, service := range services {
+		serviceNames[service.ID] = service.Spec.Name
+	}
+
+	// Tasks that are being shut down still have the running state for a while, so we only want the ones
+	// swarm is trying to keep running
+	swarmTasks, err := s.client.ListTasks(docker.ListTasksOptions{
+		Filters: map[string][]string{"desired-state": {string(swarm.TaskStateRunning)}},
+	})
+	if err != nil {
+		log.Errorf("Error listing tasks: %v", err)
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, t := range swarmTasks {
+		if t.Status.State == swarm.TaskStateRunning {
+			counts[serviceNames[t.ServiceID]]++
+		}
+	}
+
+	// Set running counts. Defaults to 0 if the service does not exist.
+	for _, task := range running.Tasks {
+		task.Running = counts[task.Name]
+	}
+
+	return nil
+}
+
+// Cleanup gives the scheduler an opportunity to stop anything that needs to be stopped
+func (s *SwarmScheduler) Cleanup() error {
+	s.backoff.Stop()
+	return nil
+}
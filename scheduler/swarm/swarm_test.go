@@ -0,0 +1,207 @@
+package swarm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/fsouza/go-dockerclient"
+
+	"github.com/microscaling/microscaling/demand"
+)
+
+// fakeSwarm is a stand-in for the parts of the swarm API that we use
+type fakeSwarm struct {
+	t        *testing.T
+	version  uint64
+	replicas uint64
+	conflict bool
+	updates  int
+}
+
+func (f *fakeSwarm) service() swarm.Service {
+	replicas := f.replicas
+	s := swarm.Service{ID: "abc123"}
+	s.Version.Index = f.version
+	s.Spec.Name = "web"
+	s.Spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &replicas}
+	s.Spec.TaskTemplate.Resources = &swarm.ResourceRequirements{
+		Reservations: &swarm.Resources{NanoCPUs: 250000000, MemoryBytes: 128 * 1024 * 1024},
+	}
+	return s
+}
+
+func (f *fakeSwarm) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/services/web":
+		json.NewEncoder(w).Encode(f.service())
+
+	case "/services/ops":
+		s := swarm.Service{ID: "def456"}
+		s.Spec.Name = "ops"
+		s.Spec.Mode.Global = &swarm.GlobalService{}
+		json.NewEncoder(w).Encode(s)
+
+	case "/services/abc123/update":
+		if f.conflict {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"rpc error: code = 2 desc = update out of sequence"}`))
+			return
+		}
+
+		if r.URL.Query().Get("version") != "10" {
+			f.t.Errorf("Expected the version we read but was %s", r.URL.Query().Get("version"))
+		}
+
+		var spec swarm.ServiceSpec
+		json.NewDecoder(r.Body).Decode(&spec)
+		f.replicas = *spec.Mode.Replicated.Replicas
+		f.version++
+		f.updates++
+
+	case "/services":
+		json.NewEncoder(w).Encode([]swarm.Service{f.service()})
+
+	case "/tasks":
+		var filters map[string][]string
+		json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+		if len(filters["desired-state"]) != 1 || filters["desired-state"][0] != "running" {
+			f.t.Errorf("Expected to filter on desired state but filters were %v", filters)
+		}
+
+		tasks := []swarm.Task{
+			{ServiceID: "abc123", Status: swarm.TaskStatus{State: swarm.TaskStateRunning}},
+			{ServiceID: "abc123", Status: swarm.TaskStatus{State: swarm.TaskStateRunning}},
+			{ServiceID: "abc123", Status: swarm.TaskStatus{State: swarm.TaskStateStarting}},
+			{ServiceID: "other", Status: swarm.TaskStatus{State: swarm.TaskStateRunning}},
+		}
+		json.NewEncoder(w).Encode(tasks)
+
+	case "/nodes":
+		nodes := make([]swarm.Node, 3)
+		for i := range nodes {
+			nodes[i].Spec.Availability = swarm.NodeAvailabilityActive
+			nodes[i].Status.State = swarm.NodeStateReady
+			nodes[i].Description.Resources = swarm.Resources{NanoCPUs: 2000000000, MemoryBytes: 4 * 1024 * 1024 * 1024}
+		}
+		nodes[2].Spec.Availability = swarm.NodeAvailabilityDrain
+		json.NewEncoder(w).Encode(nodes)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestScheduler(t *testing.T, f *fakeSwarm) (*SwarmScheduler, func()) {
+	server := httptest.NewServer(f)
+	s := NewScheduler(server.URL, make(chan struct{}, 1))
+	if s == nil {
+		t.Fatalf("Failed to create scheduler")
+	}
+
+	return s, func() {
+		s.Cleanup()
+		server.Close()
+	}
+}
+
+func TestSwarmInitScheduler(t *testing.T) {
+	s, cleanup := newTestScheduler(t, &fakeSwarm{t: t})
+	defer cleanup()
+
+	task := demand.Task{Name: "web"}
+	if err := s.InitScheduler(&task); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if task.Resources.CPU != 250 || task.Resources.Memory != 128*1024*1024 {
+		t.Fatalf("Expected resources from the reservations but got %+v", task.Resources)
+	}
+
+	if err := s.InitScheduler(&demand.Task{Name: "ops"}); err == nil {
+		t.Fatalf("Expected an error for a global service")
+	}
+
+	if err := s.InitScheduler(&demand.Task{Name: "missing"}); err == nil {
+		t.Fatalf("Expected an error for a missing service")
+	}
+}
+
+func TestSwarmScheduler(t *testing.T) {
+	f := &fakeSwarm{t: t, version: 10, replicas: 2}
+	s, cleanup := newTestScheduler(t, f)
+	defer cleanup()
+
+	web := &demand.Task{Name: "web", Demand: 2}
+	missing := &demand.Task{Name: "missing", Demand: 2, Running: 2}
+	tasks := &demand.Tasks{Tasks: []*demand.Task{web, missing}}
+
+	if err := s.CountAllTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if web.Running != 2 || missing.Running != 0 {
+		t.Fatalf("Expected 2 and 0 running but have %d and %d", web.Running, missing.Running)
+	}
+
+	// Someone else updated the service, so we back off
+	missing.Demand = 0
+	web.Requested = 2
+	web.Demand = 4
+	f.conflict = true
+	if err := s.StopStartTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if web.Requested != 2 || !s.backoff.Waiting() {
+		t.Fatalf("Expected to back off after a conflict")
+	}
+
+	// Still waiting, so we don't try again yet
+	f.conflict = false
+	s.StopStartTasks(tasks)
+	if f.updates != 0 {
+		t.Fatalf("Shouldn't update while backed off")
+	}
+
+	// Try again when the backoff tells us to
+	<-s.demandUpdate
+	if err := s.StopStartTasks(tasks); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if f.updates != 1 || f.replicas != 4 || web.Requested != 4 {
+		t.Fatalf("Expected 4 replicas but have %d after %d updates, %d requested", f.replicas, f.updates, web.Requested)
+	}
+}
+
+func TestSwarmDiscoverCapacity(t *testing.T) {
+	s, cleanup := newTestScheduler(t, &fakeSwarm{t: t})
+	defer cleanup()
+
+	r, err := s.DiscoverCapacity()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// The drained node doesn't count
+	if r.CPU != 4000 || r.Memory != 8*1024*1024*1024 {
+		t.Fatalf("Expected 2 nodes' worth of resources but got %+v", r)
+	}
+}
+
+func TestIsConflict(t *testing.T) {
+	if !isConflict(&docker.Error{Status: http.StatusConflict}) {
+		t.Errorf("409 should be a conflict")
+	}
+
+	if isConflict(&docker.Error{Status: http.StatusInternalServerError, Message: "no space left on device"}) {
+		t.Errorf("Other errors shouldn't be a conflict")
+	}
+
+	if isConflict(nil) {
+		t.Errorf("No error isn't a conflict")
+	}
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/op/go-logging"
 	"golang.org/x/net/websocket"
@@ -16,25 +18,42 @@ import (
 	"github.com/microscaling/microscaling/monitor"
 	"github.com/microscaling/microscaling/scheduler"
 	"github.com/microscaling/microscaling/scheduler/docker"
+	"github.com/microscaling/microscaling/scheduler/ecs"
 	"github.com/microscaling/microscaling/scheduler/kubernetes"
 	"github.com/microscaling/microscaling/scheduler/marathon"
+	"github.com/microscaling/microscaling/scheduler/nomad"
+	"github.com/microscaling/microscaling/scheduler/swarm"
 	"github.com/microscaling/microscaling/scheduler/toy"
+	"github.com/microscaling/microscaling/utils"
 )
 
 type settings struct {
-	schedulerType   string
-	sendMetrics     bool
-	monitorTypes    string
-	microscalingAPI string
-	userID          string
-	pullImages      bool
-	dockerHost      string
-	demandEngine    string
-	marathonAPI     string
-	config          string
-	kubeConfig      string
-	kubeNamespace   string
-	configData      string
+	schedulerType    string
+	sendMetrics      bool
+	monitorTypes     string
+	prometheusPort   string
+	microscalingAPI  string
+	userID           string
+	pullImages       bool
+	dockerHost       string
+	demandEngine     string
+	marathonAPI      string
+	ecsCluster       string
+	nomadAPI         string
+	config           string
+	kubeConfig       string
+	kubeNamespace    string
+	configData       string
+	configFile       string
+	configRefresh    time.Duration
+	maxCPU           string
+	maxMemory        string
+	discoverMax      bool
+	metricStaleAfter time.Duration
+	pidTuningFile    string
+	backtestFile     string
+	backtestTask     string
+	backtestStartup  time.Duration
 }
 
 func initLogging() {
@@ -57,7 +76,7 @@ func initLogging() {
 	logBackend := logging.NewLogBackend(os.Stdout, "", 0)
 	logging.SetBackend(logBackend)
 
-	var components = []string{"mssagent", "mssapi", "mssconfig", "mssdemand", "mssengine", "mssmetric", "mssscheduler", "msstarget", "mssutils"}
+	var components = []string{"mssagent", "mssapi", "mssconfig", "mssdemand", "mssengine", "mssforecast", "mssmetric", "mssscheduler", "msstarget", "mssutils"}
 
 	for _, component := range components {
 		if strings.Contains(logComponents, component) || strings.Contains(logComponents, "all") {
@@ -75,12 +94,31 @@ func getSettings() settings {
 	st.userID = getEnvOrDefault("MSS_USER_ID", "5k5gk")
 	st.sendMetrics = (getEnvOrDefault("MSS_SEND_METRICS_TO_API", "true") == "true")
 	st.monitorTypes = getEnvOrDefault("MSS_MONITOR", "SERVER")
+	st.prometheusPort = getEnvOrDefault("MSS_PROMETHEUS_PORT", "9102")
 	st.pullImages = (getEnvOrDefault("MSS_PULL_IMAGES", "true") == "true")
 	st.dockerHost = getEnvOrDefault("DOCKER_HOST", "unix:///var/run/docker.sock")
 	st.demandEngine = getEnvOrDefault("MSS_DEMAND_ENGINE", "LOCAL")
 	st.marathonAPI = getEnvOrDefault("MSS_MARATHON_API", "http://localhost:8080")
+	st.ecsCluster = getEnvOrDefault("MSS_ECS_CLUSTER", "default")
+	st.nomadAPI = getEnvOrDefault("NOMAD_ADDR", "http://127.0.0.1:4646")
 	st.config = getEnvOrDefault("MSS_CONFIG", "SERVER")
 	st.configData = getEnvOrDefault("MSS_CONFIG_DATA", "")
+	st.configFile = getEnvOrDefault("MSS_CONFIG_FILE", "microscaling.yml")
+	// How often to reload config, in seconds. By default we only reload on SIGHUP or when the config file changes.
+	st.configRefresh = time.Duration(getEnvIntOrDefault("MSS_CONFIG_REFRESH", 0)) * time.Second
+	// Total CPU and memory for all tasks, e.g. "4" or "4000m" CPU, "8Gi" memory. By default we only count containers.
+	st.maxCPU = getEnvOrDefault("MSS_MAX_CPU", "")
+	st.maxMemory = getEnvOrDefault("MSS_MAX_MEMORY", "")
+	// Ask the scheduler for any total that isn't set
+	st.discoverMax = (getEnvOrDefault("MSS_DISCOVER_MAX_RESOURCES", "false") == "true")
+	// Hold tasks at their current scale if their metric hasn't been read successfully for this many seconds
+	st.metricStaleAfter = time.Duration(getEnvIntOrDefault("MSS_METRIC_STALE_AFTER", 30)) * time.Second
+	// Where we save controller settings for tasks that are auto-tuned, so they survive restarts
+	st.pidTuningFile = getEnvOrDefault("MSS_PID_TUNING_FILE", "microscaling-pid.json")
+	// Replay a recorded metric for a task and report how forecasting would have changed scaling, instead of running
+	st.backtestFile = getEnvOrDefault("MSS_BACKTEST_FILE", "")
+	st.backtestTask = getEnvOrDefault("MSS_BACKTEST_TASK", "")
+	st.backtestStartup = time.Duration(getEnvIntOrDefault("MSS_BACKTEST_STARTUP_DELAY", 10)) * time.Second
 	// To run locally set kube config location. Otherwise uses the built in cluster config.
 	st.kubeConfig = getEnvOrDefault("MSS_KUBE_CONFIG", "")
 	st.kubeNamespace = getEnvOrDefault("MSS_KUBE_NAMESPACE", "default")
@@ -97,13 +135,22 @@ func getScheduler(st settings, demandUpdate chan struct{}) (scheduler.Scheduler,
 	case "MARATHON":
 		log.Info("Scheduling with Mesos / Marathon")
 		s = marathon.NewScheduler(st.marathonAPI, demandUpdate)
+	case "SWARM":
+		log.Info("Scheduling with Docker swarm mode services")
+		s = swarm.NewScheduler(st.dockerHost, demandUpdate)
 	case "ECS":
-		return nil, fmt.Errorf("Scheduling with ECS not yet supported. Tweet with hashtag #MicroscaleECS if you'd like us to add this next!")
+		log.Info("Scheduling with Amazon ECS")
+		e, err := ecs.NewScheduler(st.ecsCluster)
+		if err != nil {
+			return nil, err
+		}
+		s = e
 	case "KUBERNETES":
 		log.Info("Scheduling with Kubernetes")
 		s = kubernetes.NewScheduler(st.kubeConfig, st.kubeNamespace, demandUpdate)
 	case "NOMAD":
-		return nil, fmt.Errorf("Scheduling with Nomad not yet supported. Tweet with hashtag #MicroscaleNomad if you'd like us to add this next!")
+		log.Info("Scheduling with Nomad")
+		s = nomad.NewScheduler(st.nomadAPI, demandUpdate)
 	case "TOY":
 		log.Info("Scheduling with toy scheduler")
 		s = toy.NewScheduler()
@@ -118,15 +165,11 @@ func getScheduler(st settings, demandUpdate chan struct{}) (scheduler.Scheduler,
 	return s, nil
 }
 
-func getTasks(st settings) (tasks *demand.Tasks, err error) {
-	var c config.Config
-
-	tasks = new(demand.Tasks)
-
+func getConfig(st settings) (c config.Config, err error) {
 	// Get the tasks that have been configured by this user
 	switch st.config {
 	case "FILE":
-		return nil, fmt.Errorf("Config file not yet supported")
+		c = config.NewFileConfig(st.configFile)
 	case "SERVER":
 		c = config.NewServerConfig(st.microscalingAPI)
 	case "HARDCODED":
@@ -148,9 +191,35 @@ func getTasks(st settings) (tasks *demand.Tasks, err error) {
 		return nil, fmt.Errorf("Bad value for MSS_CONFIG: %s", st.config)
 	}
 
+	return c, nil
+}
+
+func getTasks(st settings) (tasks *demand.Tasks, err error) {
+	c, err := getConfig(st)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadTasks(c, st)
+}
+
+func loadTasks(c config.Config, st settings) (tasks *demand.Tasks, err error) {
+	tasks = new(demand.Tasks)
+
 	t, maxContainers, err := c.GetApps(st.userID)
 	tasks.MaxContainers = maxContainers
 
+	setTaskEnv(t)
+	tasks.Tasks = t
+
+	if err != nil {
+		log.Errorf("Error getting tasks: %v", err)
+	}
+
+	return tasks, err
+}
+
+func setTaskEnv(t []*demand.Task) {
 	// For now pass the whole environment to all containers.
 	globalEnv := os.Environ()
 
@@ -158,21 +227,50 @@ func getTasks(st settings) (tasks *demand.Tasks, err error) {
 		task.Env = globalEnv
 		log.Debugf("%+v", task)
 	}
+}
 
-	tasks.Tasks = t
+func getMaxResources(st settings, s scheduler.Scheduler) (r demand.Resources, err error) {
+	r.CPU, err = utils.ParseCPU(st.maxCPU)
+	if err != nil {
+		return r, fmt.Errorf("Bad value for MSS_MAX_CPU: %v", err)
+	}
 
+	r.Memory, err = utils.ParseMemory(st.maxMemory)
 	if err != nil {
-		log.Errorf("Error getting tasks: %v", err)
+		return r, fmt.Errorf("Bad value for MSS_MAX_MEMORY: %v", err)
 	}
 
-	return tasks, err
+	if !st.discoverMax || (r.CPU > 0 && r.Memory > 0) {
+		return r, nil
+	}
+
+	d, ok := s.(scheduler.CapacityDiscoverer)
+	if !ok {
+		return r, fmt.Errorf("Scheduler %s can't discover CPU and memory, please set MSS_MAX_CPU and MSS_MAX_MEMORY", st.schedulerType)
+	}
+
+	discovered, err := d.DiscoverCapacity()
+	if err != nil {
+		return r, fmt.Errorf("Failed to discover CPU and memory: %v", err)
+	}
+
+	if r.CPU == 0 {
+		r.CPU = discovered.CPU
+	}
+
+	if r.Memory == 0 {
+		r.Memory = discovered.Memory
+	}
+
+	log.Infof("Discovered CPU %dm, memory %d", discovered.CPU, discovered.Memory)
+	return r, nil
 }
 
 func getDemandEngine(st settings, ws *websocket.Conn) (e engine.Engine, err error) {
 	switch st.demandEngine {
 	case "LOCAL":
 		log.Info("Calculate demand locally")
-		e = localEngine.NewEngine()
+		e = localEngine.NewEngine(st.metricStaleAfter)
 	case "SERVER":
 		log.Info("Get demand from server")
 		e = serverEngine.NewEngine(ws)
@@ -182,7 +280,7 @@ func getDemandEngine(st settings, ws *websocket.Conn) (e engine.Engine, err erro
 	return e, nil
 }
 
-func getMonitors(st settings, ws *websocket.Conn) (m []monitor.Monitor) {
+func getMonitors(st settings, ws *websocket.Conn) (m []monitor.Monitor, err error) {
 	// Monitor is where we send results & output. There might be more than one so we return a list
 	if strings.Contains(st.monitorTypes, "SERVER") {
 		log.Info("Server is a monitor")
@@ -190,6 +288,16 @@ func getMonitors(st settings, ws *websocket.Conn) (m []monitor.Monitor) {
 		m = append(m, ms)
 	}
 
+	if strings.Contains(st.monitorTypes, "PROMETHEUS") {
+		log.Infof("Serving Prometheus metrics on port %s", st.prometheusPort)
+		mp := monitor.NewPrometheusMonitor()
+		err = mp.Listen(st.prometheusPort)
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, mp)
+	}
+
 	return
 }
 
@@ -201,3 +309,18 @@ func getEnvOrDefault(name string, defaultValue string) string {
 
 	return v
 }
+
+func getEnvIntOrDefault(name string, defaultValue int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Errorf("Bad value for %s, using default %d: %v", name, defaultValue, err)
+		return defaultValue
+	}
+
+	return i
+}
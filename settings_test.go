@@ -6,6 +6,8 @@ import (
 	// "strconv"
 	"testing"
 	// "time"
+
+	"github.com/microscaling/microscaling/scheduler/toy"
 )
 
 func TestSettings(t *testing.T) {
@@ -24,21 +26,24 @@ func TestInitScheduler(t *testing.T) {
 	var err error
 
 	tests := []struct {
-		sched string
-		pass  bool
+		sched     string
+		awsRegion string
+		pass      bool
 	}{
 		{sched: "COMPOSE", pass: false},
 		{sched: "DOCKER", pass: true},
 		{sched: "ECS", pass: false},
+		{sched: "ECS", awsRegion: "us-east-1", pass: true},
 		{sched: "KUBERNETES", pass: true},
 		{sched: "MESOS", pass: false},
-		{sched: "NOMAD", pass: false},
+		{sched: "NOMAD", pass: true},
 		{sched: "TOY", pass: true},
 		{sched: "BLAH", pass: false},
 	}
 
 	for _, test := range tests {
 		os.Setenv("MSS_SCHEDULER", test.sched)
+		os.Setenv("AWS_REGION", test.awsRegion)
 		st := getSettings()
 		_, err = getScheduler(st, nil)
 		if err != nil && test.pass {
@@ -48,6 +53,8 @@ func TestInitScheduler(t *testing.T) {
 			t.Fatalf("Should not have been able to create %s", test.sched)
 		}
 	}
+
+	os.Unsetenv("AWS_REGION")
 }
 
 func TestInitConfig(t *testing.T) {
@@ -79,3 +86,44 @@ func TestInitConfig(t *testing.T) {
 	}
 
 }
+
+func TestGetMaxResources(t *testing.T) {
+	tests := []struct {
+		maxCPU      string
+		maxMemory   string
+		discoverMax bool
+		cpu         int64
+		memory      int64
+		pass        bool
+	}{
+		{pass: true},
+		{maxCPU: "4", maxMemory: "1Gi", cpu: 4000, memory: 1024 * 1024 * 1024, pass: true},
+		{maxCPU: "1500m", cpu: 1500, pass: true},
+		{maxCPU: "lots", pass: false},
+		{maxMemory: "lots", pass: false},
+		// The toy scheduler can't discover resources
+		{maxCPU: "4", discoverMax: true, pass: false},
+		// But we don't need to discover anything if everything is configured
+		{maxCPU: "4", maxMemory: "1Gi", discoverMax: true, cpu: 4000, memory: 1024 * 1024 * 1024, pass: true},
+	}
+
+	for _, test := range tests {
+		st := settings{
+			schedulerType: "TOY",
+			maxCPU:        test.maxCPU,
+			maxMemory:     test.maxMemory,
+			discoverMax:   test.discoverMax,
+		}
+
+		r, err := getMaxResources(st, toy.NewScheduler())
+		if err != nil && test.pass {
+			t.Fatalf("Unexpected error for %+v: %v", test, err)
+		}
+		if err == nil && !test.pass {
+			t.Fatalf("Expected an error for %+v", test)
+		}
+		if test.pass && (r.CPU != test.cpu || r.Memory != test.memory) {
+			t.Fatalf("Expected CPU %d memory %d but got %+v", test.cpu, test.memory, r)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package target
+
+import (
+	"math"
+)
+
+const constTuningCycles = 4         // oscillations we watch before working out the gains. We ignore the first one.
+const constMaxTuningSamples = 1200  // give up if the queue hasn't oscillated enough after this many samples
+const constDefaultRelayStep int = 1 // containers above and below the starting point while we're tuning
+const constRelayPatience = 40       // samples without the queue crossing the target before we move the starting point
+
+// Observer is implemented by targets that need to know how many containers are running before working out the delta
+type Observer interface {
+	Observe(running int)
+}
+
+// relayTuner runs a relay test (Åström-Hägglund) to find the gains for a queue. We switch between running a few
+// more and a few fewer containers each time the queue goes above or below the target, and watch how the queue
+// oscillates. The size and period of the oscillation tell us the ultimate gain and period for the queue (kU and tU).
+// The queue only oscillates if the containers can keep up on the high side and not on the low side, so if it doesn't
+// cross the target for a while we move the starting point and begin again.
+type relayTuner struct {
+	step        int
+	base        int
+	started     bool
+	high        bool
+	samples     int
+	sinceSwitch int
+	lastSwitch  int
+	max         int
+	min         int
+	periods     []int
+	amplitudes  []float64
+}
+
+func newRelayTuner(step int) *relayTuner {
+	if step <= 0 {
+		step = constDefaultRelayStep
+	}
+
+	return &relayTuner{
+		step:       step,
+		lastSwitch: -1,
+	}
+}
+
+// update takes the latest queue length, and returns the number of containers we want running and whether
+// we've finished tuning
+func (r *relayTuner) update(running int, current int, length int, minLength int) (want int, done bool) {
+	if !r.started {
+		// Start from what's running now, but we need to be able to go down by a full step
+		r.base = running
+		if r.base < r.step {
+			r.base = r.step
+		}
+
+		r.high = current > length
+		r.max = current
+		r.min = current
+		r.started = true
+	}
+
+	r.samples++
+	r.sinceSwitch++
+	if current > r.max {
+		r.max = current
+	}
+	if current < r.min {
+		r.min = current
+	}
+
+	// We switch with the same hysteresis as Meeting and Exceeding, so the scaling calculation follows the relay
+	if !r.high && current > length {
+		r.high = true
+
+		// A full cycle is from one upward switch to the next
+		if r.lastSwitch >= 0 {
+			r.periods = append(r.periods, r.samples-r.lastSwitch)
+			r.amplitudes = append(r.amplitudes, float64(r.max-r.min)/2.0)
+		}
+
+		r.lastSwitch = r.samples
+		r.sinceSwitch = 0
+		r.max = current
+		r.min = current
+	} else if r.high && current <= minLength {
+		r.high = false
+		r.sinceSwitch = 0
+	} else if r.sinceSwitch >= constRelayPatience {
+		r.moveBase(current)
+	}
+
+	if r.high {
+		want = r.base + r.step
+	} else {
+		want = r.base - r.step
+	}
+
+	return want, len(r.periods) >= constTuningCycles || r.samples >= constMaxTuningSamples
+}
+
+// moveBase starts again from a higher starting point if the queue won't come down, or lower if it won't go up
+func (r *relayTuner) moveBase(current int) {
+	if r.high {
+		r.base++
+	} else if r.base > r.step {
+		r.base--
+	}
+
+	log.Debugf("[ql] tuning: queue hasn't crossed the target, starting again from %d containers", r.base)
+	r.sinceSwitch = 0
+	r.lastSwitch = -1
+	r.max = current
+	r.min = current
+	r.periods = nil
+	r.amplitudes = nil
+}
+
+// result works out kU and tU from the oscillations we've seen. ok is false if we didn't see enough of them.
+func (r *relayTuner) result(length int, minLength int) (kU float64, tU float64, ok bool) {
+	// The first cycle is affected by where we started, so we don't use it
+	if len(r.periods) < 2 {
+		return 0, 0, false
+	}
+
+	var a float64
+	for i := 1; i < len(r.periods); i++ {
+		tU += float64(r.periods[i])
+		a += r.amplitudes[i]
+	}
+
+	n := float64(len(r.periods) - 1)
+	tU = tU / n
+	a = a / n
+
+	if a <= 0 {
+		return 0, 0, false
+	}
+
+	// Allow for the hysteresis between switching up and down
+	e := float64(length-minLength) / 2.0
+	if a > e {
+		a = math.Sqrt(a*a - e*e)
+	}
+
+	kU = 4.0 * float64(r.step) / (math.Pi * a)
+	return kU, tU, true
+}
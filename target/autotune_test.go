@@ -0,0 +1,193 @@
+package target
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// simulatedQueue is a simple model of a queue. Items arrive at a steady rate, each container works through
+// a fixed number of items per sample, and it takes a few samples for a change in scale to take effect.
+type simulatedQueue struct {
+	length    float64
+	arrivals  float64
+	perWorker float64
+	deadTime  int
+	running   int
+	requested int
+	pending   []int
+	max       int
+}
+
+func (s *simulatedQueue) step() {
+	// Containers we asked for a while ago have now started (or stopped)
+	s.pending = append(s.pending, s.requested)
+	if len(s.pending) > s.deadTime {
+		s.running = s.pending[0]
+		s.pending = s.pending[1:]
+	}
+
+	s.length += s.arrivals - s.perWorker*float64(s.running)
+	if s.length < 0 {
+		s.length = 0
+	}
+}
+
+// scale does what the scaling calculation would do with the target's delta
+func (s *simulatedQueue) scale(q *QueueLengthTarget) {
+	current := int(s.length)
+	q.Observe(s.running)
+	delta := q.Delta(current)
+
+	if s.running != s.requested {
+		// There's a scale operation in progress
+		return
+	}
+
+	if (delta > 0 && !q.Meeting(current)) || (delta < 0 && q.Exceeding(current)) {
+		s.requested = s.running + delta
+	}
+
+	if s.requested < 0 {
+		s.requested = 0
+	}
+	if s.requested > s.max {
+		s.requested = s.max
+	}
+}
+
+func newSimulatedQueue() *simulatedQueue {
+	// 5 containers keep up with the arrivals
+	return &simulatedQueue{
+		length:    200,
+		arrivals:  10,
+		perWorker: 2,
+		deadTime:  3,
+		running:   3,
+		requested: 3,
+		max:       20,
+	}
+}
+
+func TestAutoTune(t *testing.T) {
+	SetTuningStore(nil)
+
+	sim := newSimulatedQueue()
+	q := NewQueueLengthTarget(50)
+	q.AutoTune("consumer", 1)
+
+	samples := 0
+	for q.tuner != nil {
+		sim.scale(q)
+		sim.step()
+		samples++
+		if samples > constMaxTuningSamples {
+			t.Fatalf("Tuning didn't finish")
+		}
+	}
+
+	if !q.tuned {
+		t.Fatalf("Queue should be tuned after %d samples", samples)
+	}
+
+	if q.kP <= 0 || q.kD <= 0 || *q.pid.TU <= 0 || *q.pid.KU <= 0 {
+		t.Fatalf("Unexpected gains kP %f kD %f kU %f tU %f", q.kP, q.kD, *q.pid.KU, *q.pid.TU)
+	}
+
+	// Now the controller should settle with the queue close to the target
+	var totalErr float64
+	minRunning, maxRunning := sim.max, 0
+	for i := 0; i < 400; i++ {
+		sim.scale(q)
+		sim.step()
+		if i >= 300 {
+			totalErr += math.Abs(sim.length - 50)
+			if sim.running < minRunning {
+				minRunning = sim.running
+			}
+			if sim.running > maxRunning {
+				maxRunning = sim.running
+			}
+		}
+	}
+
+	t.Logf("Tuned after %d samples: kU %f tU %f, average error %f", samples, *q.pid.KU, *q.pid.TU, totalErr/100)
+	if totalErr/100 > 15 {
+		t.Fatalf("Average error %f is too big with kP %f kD %f", totalErr/100, q.kP, q.kD)
+	}
+
+	if maxRunning-minRunning > 1 {
+		t.Fatalf("Still oscillating between %d and %d containers", minRunning, maxRunning)
+	}
+}
+
+func TestAutoTuneNoOscillation(t *testing.T) {
+	SetTuningStore(nil)
+
+	// The queue never gets as long as the target, so it can't oscillate
+	q := NewTunedQueueLengthTarget(50, PIDConfig{KP: floatPtr(1), KD: floatPtr(2)})
+	q.AutoTune("idle", 1)
+
+	for i := 0; i < constMaxTuningSamples; i++ {
+		q.Observe(2)
+		q.Delta(0)
+	}
+
+	if q.tuner != nil || q.tuned {
+		t.Fatalf("Tuning should have given up")
+	}
+
+	if q.kP != 1 || q.kD != 2 {
+		t.Fatalf("Gains should be unchanged")
+	}
+}
+
+func TestAutoTunePersisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microscaling")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileTuningStore(filepath.Join(dir, "tuning.json"))
+	SetTuningStore(store)
+	defer SetTuningStore(nil)
+
+	sim := newSimulatedQueue()
+	q := NewQueueLengthTarget(50)
+	q.AutoTune("consumer", 1)
+	for i := 0; i < constMaxTuningSamples && q.tuner != nil; i++ {
+		sim.scale(q)
+		sim.step()
+	}
+
+	saved, ok := store.Load("consumer")
+	if !ok {
+		t.Fatalf("Tuned gains weren't saved")
+	}
+
+	if *saved.KP != q.kP || *saved.KD != q.kD {
+		t.Fatalf("Saved gains %f %f don't match %f %f", *saved.KP, *saved.KD, q.kP, q.kD)
+	}
+
+	// After a restart we use the saved gains straight away
+	restarted := NewQueueLengthTarget(50)
+	restarted.AutoTune("consumer", 1)
+	if restarted.tuner != nil || restarted.kP != q.kP || restarted.kD != q.kD {
+		t.Fatalf("Should have used saved gains")
+	}
+
+	// But a different task still needs tuning
+	other := NewQueueLengthTarget(50)
+	other.AutoTune("other", 1)
+	if other.tuner == nil {
+		t.Fatalf("Should be tuning a task without saved gains")
+	}
+
+	// Reloading config keeps the tuned gains
+	if !restarted.Reconfigure(other) || restarted.tuner != nil || restarted.kP != q.kP {
+		t.Fatalf("Reconfiguring shouldn't lose the tuned gains")
+	}
+}
@@ -0,0 +1,226 @@
+package target
+
+import (
+	"math"
+	"time"
+)
+
+// DrainTimeTarget aims to process every message within a deadline, rather than keeping the queue under a fixed
+// length. We estimate how long it would take to drain the queue from its length, how many messages each container
+// gets through, and how quickly new messages are arriving, and run enough containers to drain it in time.
+type DrainTimeTarget struct {
+	deadline time.Duration
+	running  int
+	now      time.Time
+
+	// The dequeue rate from the metric, if it can measure one, and when it was measured from
+	rate      float64
+	rateSince time.Time
+	hasRate   bool
+
+	// When the number of running containers last changed, so we don't learn from a rate measured before then
+	lastRunning  int
+	runningSince time.Time
+
+	lastLength int
+	lastAt     time.Time
+	vel        queueVelocity
+	velocity   float64 // how quickly the queue is growing, in messages per second, averaged over vel
+	arrivals   float64 // how quickly messages are being added, in messages per second
+	throughput float64 // messages per second for each container, or 0 if we don't know yet
+}
+
+// compile-time assert that we implement the right interfaces
+var _ Target = (*DrainTimeTarget)(nil)
+var _ Observer = (*DrainTimeTarget)(nil)
+var _ RateObserver = (*DrainTimeTarget)(nil)
+var _ Timed = (*DrainTimeTarget)(nil)
+var _ Reconfigurable = (*DrainTimeTarget)(nil)
+
+// We only scale down if we'd still drain the queue within this fraction of the deadline, so that we don't keep
+// adding and removing a container when we're close to it
+const drainTimeExceedingPercent float64 = 0.7
+
+// How much weight each new throughput sample gets, as it's noisy from one reading to the next
+const drainThroughputSmoothing float64 = 0.3
+
+// NewDrainTimeTarget creates a new target that drains the queue within the deadline
+func NewDrainTimeTarget(deadline time.Duration) *DrainTimeTarget {
+	_, _, _, velSamples := PIDConfig{}.gains()
+	return &DrainTimeTarget{
+		deadline: deadline,
+		vel:      newQueueVelocity(velSamples),
+	}
+}
+
+// Observe tells us how many containers are running
+func (t *DrainTimeTarget) Observe(running int) {
+	t.running = running
+}
+
+// ObserveRate tells us how many messages per second are being taken off the queue, measured since the given time
+func (t *DrainTimeTarget) ObserveRate(rate float64, since time.Time) {
+	t.rate = rate
+	t.rateSince = since
+	t.hasRate = true
+}
+
+// At tells us the time, so we can work out how quickly the queue length is changing
+func (t *DrainTimeTarget) At(now time.Time) {
+	t.now = now
+}
+
+// update learns what we can about the queue from the latest length
+func (t *DrainTimeTarget) update(current int) {
+	if !t.lastAt.IsZero() && t.now.After(t.lastAt) {
+		t.velocity = t.vel.add(float64(current-t.lastLength) / t.now.Sub(t.lastAt).Seconds())
+	}
+
+	// We don't know how long the first containers we see have been running
+	if t.running != t.lastRunning && !t.lastAt.IsZero() {
+		t.runningSince = t.now
+	}
+	t.lastRunning = t.running
+
+	// The containers are only working flat out if there were messages waiting the whole time
+	busy := current > 0 && (t.lastAt.IsZero() || t.lastLength > 0)
+
+	// Some metrics lag behind, e.g. SQS gets its rate from CloudWatch, which can be minutes old. If we've scaled
+	// since it was measured, a different number of containers did the work.
+	rateIsCurrent := t.rateSince.IsZero() || !t.rateSince.Before(t.runningSince)
+
+	if t.running > 0 {
+		switch {
+		case t.hasRate && busy && t.rate > 0:
+			if rateIsCurrent {
+				t.learnThroughput(t.rate / float64(t.running))
+			}
+		case !t.hasRate && t.velocity < 0:
+			// Without a dequeue rate all we know is that if the queue is going down, each container is
+			// getting through at least this many messages
+			if sample := -t.velocity / float64(t.running); sample > t.throughput {
+				t.learnThroughput(sample)
+			}
+		}
+	}
+
+	// Anything the containers have taken off the queue has been replaced by new arrivals, as well as any growth.
+	// A rate from before we last scaled would underestimate that after scaling up.
+	switch {
+	case t.hasRate && rateIsCurrent:
+		t.arrivals = math.Max(0, t.rate+t.velocity)
+	case busy:
+		t.arrivals = math.Max(0, t.throughput*float64(t.running)+t.velocity)
+	default:
+		t.arrivals = math.Max(0, t.velocity)
+	}
+
+	t.lastLength = current
+	t.lastAt = t.now
+	t.hasRate = false
+}
+
+func (t *DrainTimeTarget) learnThroughput(sample float64) {
+	if t.throughput == 0 {
+		t.throughput = sample
+		return
+	}
+	t.throughput += drainThroughputSmoothing * (sample - t.throughput)
+}
+
+// DrainTime estimates how long it would take the running containers to get through everything on the queue,
+// while keeping up with new messages. It's infinite if they can't keep up.
+func (t *DrainTimeTarget) DrainTime(current int) time.Duration {
+	if current <= 0 {
+		return 0
+	}
+
+	spare := t.throughput*float64(t.running) - t.arrivals
+	if spare <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	seconds := float64(current) / spare
+	if seconds >= float64(math.MaxInt64/int64(time.Second)) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// desired is the number of containers we need to drain the queue within the deadline
+func (t *DrainTimeTarget) desired(current int, deadline time.Duration) int {
+	if deadline <= 0 || t.throughput <= 0 {
+		return t.running
+	}
+
+	need := float64(current)/deadline.Seconds() + t.arrivals
+	return int(math.Ceil(need / t.throughput))
+}
+
+// Meeting returns true if we expect to drain the queue within the deadline. Until we know how quickly
+// containers get through the queue, we only know we're not meeting it if nothing is running.
+func (t *DrainTimeTarget) Meeting(current int) bool {
+	var meeting bool
+	if t.throughput == 0 {
+		meeting = current <= 0 || t.running > 0
+	} else {
+		meeting = t.DrainTime(current) <= t.deadline
+	}
+
+	if !meeting {
+		log.Debugf("[drain] not meeting: current %d running %d deadline %v", current, t.running, t.deadline)
+	}
+	return meeting
+}
+
+// Exceeding returns true if we could drain the queue well within the deadline with fewer containers
+func (t *DrainTimeTarget) Exceeding(current int) bool {
+	if t.throughput == 0 || t.running == 0 {
+		return false
+	}
+
+	deadline := time.Duration(float64(t.deadline) * drainTimeExceedingPercent)
+	exceeding := t.desired(current, deadline) < t.running
+	if exceeding {
+		log.Debugf("[drain] exceeding: current %d running %d deadline %v", current, t.running, t.deadline)
+	}
+	return exceeding
+}
+
+// Delta returns the number of containers to add (remove if negative). We add as many as we need to meet the
+// deadline straight away, but only remove one at a time as our estimate of the arrival rate is rough.
+func (t *DrainTimeTarget) Delta(current int) (delta int) {
+	t.update(current)
+
+	switch {
+	case t.throughput == 0:
+		// We don't know how quickly a container works through the queue yet, so add one if there's work and
+		// nothing running, or the queue is growing
+		if current > 0 && (t.running == 0 || t.velocity > 0) {
+			delta = 1
+		}
+
+	case !t.Meeting(current):
+		delta = t.desired(current, t.deadline) - t.running
+		if delta < 1 {
+			delta = 1
+		}
+
+	case t.Exceeding(current):
+		delta = -1
+	}
+
+	log.Debugf("[drain] current %d running %d throughput %f arrivals %f -> delta %d", current, t.running, t.throughput, t.arrivals, delta)
+	return delta
+}
+
+// Reconfigure takes on the deadline from another drain time target, keeping what we've learnt about the queue
+func (t *DrainTimeTarget) Reconfigure(latest Target) bool {
+	l, ok := latest.(*DrainTimeTarget)
+	if !ok {
+		return false
+	}
+
+	t.deadline = l.deadline
+	return true
+}
@@ -0,0 +1,121 @@
+package target
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDrainTimeWithRate(t *testing.T) {
+	d := NewDrainTimeTarget(60 * time.Second)
+	start := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// 2 containers getting through 10 messages a second between them, but the queue isn't going down
+	d.Observe(2)
+	d.ObserveRate(10, time.Time{})
+	d.At(start)
+	if delta := d.Delta(600); delta != 2 {
+		t.Fatalf("Expected delta 2 to drain the queue in time but was %d", delta)
+	}
+
+	if d.Meeting(600) {
+		t.Fatalf("Can't meet the deadline if the queue isn't going down")
+	}
+
+	// Now there are 4 and the queue is going down by 20 a second, so it drains in 20s
+	d.Observe(4)
+	d.ObserveRate(20, time.Time{})
+	d.At(start.Add(10 * time.Second))
+	if delta := d.Delta(400); delta != -1 {
+		t.Fatalf("Expected delta -1 as we're well within the deadline but was %d", delta)
+	}
+
+	if dt := d.DrainTime(400); dt != 20*time.Second {
+		t.Fatalf("Expected drain time 20s but was %v", dt)
+	}
+
+	if !d.Meeting(400) || !d.Exceeding(400) {
+		t.Fatalf("Should be meeting and exceeding the target")
+	}
+
+	if d.Exceeding(1000) {
+		t.Fatalf("Shouldn't be exceeding with a longer queue")
+	}
+}
+
+func TestDrainTimeFromVelocity(t *testing.T) {
+	d := NewDrainTimeTarget(30 * time.Second)
+	start := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// Nothing running, so start one
+	d.Observe(0)
+	d.At(start)
+	if delta := d.Delta(100); delta != 1 {
+		t.Fatalf("Expected delta 1 with nothing running but was %d", delta)
+	}
+
+	// We don't know the throughput yet, but the queue is growing
+	d.Observe(1)
+	d.At(start.Add(10 * time.Second))
+	if delta := d.Delta(150); delta != 1 {
+		t.Fatalf("Expected delta 1 while the queue is growing but was %d", delta)
+	}
+
+	// Going down by 2 a second with 2 containers, so each gets through at least 1 a second
+	d.Observe(2)
+	d.At(start.Add(20 * time.Second))
+	if delta := d.Delta(130); delta != 3 {
+		t.Fatalf("Expected delta 3 to drain the queue in 30s but was %d", delta)
+	}
+
+	// A longer deadline keeps what we've learnt about the queue
+	if !d.Reconfigure(NewDrainTimeTarget(120 * time.Second)) {
+		t.Fatalf("Failed to reconfigure")
+	}
+
+	d.Observe(5)
+	d.At(start.Add(30 * time.Second))
+	if delta := d.Delta(80); delta != -1 {
+		t.Fatalf("Expected delta -1 but was %d", delta)
+	}
+
+	if d.Reconfigure(NewLatencyTarget(100)) {
+		t.Fatalf("Shouldn't reconfigure from a different type of target")
+	}
+}
+
+func TestDrainTimeLaggingRate(t *testing.T) {
+	d := NewDrainTimeTarget(60 * time.Second)
+	start := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// 2 containers getting through 5 a second each
+	d.Observe(2)
+	d.ObserveRate(10, start.Add(-time.Minute))
+	d.At(start)
+	d.Delta(600)
+	if d.throughput != 5 {
+		t.Fatalf("Expected throughput 5 but was %f", d.throughput)
+	}
+
+	// We've scaled to 4, but the rate is still from when 2 were running so it doesn't tell us anything new
+	d.Observe(4)
+	d.ObserveRate(10, start.Add(-time.Minute))
+	d.At(start.Add(30 * time.Second))
+	d.Delta(600)
+	if d.throughput != 5 {
+		t.Fatalf("Shouldn't learn from a rate measured before we scaled, but throughput is %f", d.throughput)
+	}
+
+	// Nor use it for arrivals, as 4 containers are keeping the queue steady now
+	if d.arrivals != 20 {
+		t.Fatalf("Expected arrivals 20/s from what the containers get through but was %f", d.arrivals)
+	}
+
+	// Now the rate is from after we scaled
+	d.Observe(4)
+	d.ObserveRate(16, start.Add(time.Minute))
+	d.At(start.Add(2 * time.Minute))
+	d.Delta(600)
+	if d.throughput >= 5 {
+		t.Fatalf("Expected to learn the lower throughput but is %f", d.throughput)
+	}
+}
@@ -1,6 +1,8 @@
 package target
 
 import (
+	"time"
+
 	"github.com/op/go-logging"
 )
 
@@ -11,4 +13,37 @@ type Target interface {
 	Delta(int) int
 }
 
+// Reconfigurable is implemented by targets that build up state over time. When the config for a task
+// is reloaded, Reconfigure takes on the settings from the newly configured target and returns true if
+// it was able to, so that we don't lose the state by replacing the target.
+type Reconfigurable interface {
+	Reconfigure(latest Target) bool
+}
+
+// Feedback is implemented by targets that need to know how much of the delta they asked for was actually
+// applied. We might not be able to give a task as many containers as it wants because of its max containers,
+// its max delta, or because higher priority tasks are using the space.
+type Feedback interface {
+	Applied(requested int, applied int)
+}
+
+// Timed is implemented by targets that depend on the time of day. We tell them the time before asking for
+// the delta, so that we can use a different clock when testing.
+type Timed interface {
+	At(now time.Time)
+}
+
+// RateObserver is implemented by targets that need to know how quickly the queue is being worked through. If
+// the metric can measure it, we tell them the dequeue rate in messages per second before asking for the delta,
+// and when the period it was measured over started, or zero if the metric doesn't say.
+type RateObserver interface {
+	ObserveRate(rate float64, since time.Time)
+}
+
+// SetPointer is implemented by targets that aim to keep the metric at a particular value, so that we can
+// report what it is
+type SetPointer interface {
+	SetPoint() int
+}
+
 var log = logging.MustGetLogger("msstarget")
@@ -0,0 +1,104 @@
+package target
+
+import (
+	"math"
+)
+
+// LatencyTarget aims to keep a latency metric, such as the 95th percentile response time in milliseconds,
+// within a budget. We scale up hard as soon as the budget is broken, because users are waiting, but only
+// scale down one container at a time once we've had plenty of headroom for a while.
+type LatencyTarget struct {
+	budget      int
+	running     int
+	comfortable int // consecutive samples with plenty of headroom
+}
+
+// compile-time assert that we implement the right interfaces
+var _ Target = (*LatencyTarget)(nil)
+var _ Observer = (*LatencyTarget)(nil)
+var _ Reconfigurable = (*LatencyTarget)(nil)
+var _ SetPointer = (*LatencyTarget)(nil)
+
+// We have comfortable headroom when latency is below this fraction of the budget
+const latencyHeadroomPercent float64 = 0.5
+
+// How many samples in a row need comfortable headroom before we scale down a container
+const latencyScaleDownSamples int = 20
+
+// NewLatencyTarget creates a new target that keeps latency within the budget
+func NewLatencyTarget(budget int) *LatencyTarget {
+	return &LatencyTarget{
+		budget: budget,
+	}
+}
+
+// Observe tells us how many containers are running
+func (t *LatencyTarget) Observe(running int) {
+	t.running = running
+}
+
+// Meeting returns true if latency is within the budget
+func (t *LatencyTarget) Meeting(current int) bool {
+	meeting := current <= t.budget
+	if !meeting {
+		log.Debugf("[latency] not meeting: current %d budget %d", current, t.budget)
+	}
+	return meeting
+}
+
+// Exceeding returns true if latency is comfortably within the budget
+func (t *LatencyTarget) Exceeding(current int) bool {
+	exceeding := float64(current) < float64(t.budget)*latencyHeadroomPercent
+	if exceeding {
+		log.Debugf("[latency] exceeding: current %d budget %d", current, t.budget)
+	}
+	return exceeding
+}
+
+// Delta returns the number of containers to add (remove if negative). If we're over budget we add containers in
+// proportion to how far over we are, assuming latency comes down as the load is spread across more containers.
+func (t *LatencyTarget) Delta(current int) (delta int) {
+	switch {
+	case !t.Meeting(current):
+		t.comfortable = 0
+		if t.running == 0 || t.budget <= 0 {
+			delta = 1
+		} else {
+			desired := int(math.Ceil(float64(t.running) * float64(current) / float64(t.budget)))
+			delta = desired - t.running
+		}
+
+		if delta < 1 {
+			delta = 1
+		}
+
+	case t.Exceeding(current):
+		t.comfortable++
+		if t.comfortable >= latencyScaleDownSamples {
+			t.comfortable = 0
+			delta = -1
+		}
+
+	default:
+		t.comfortable = 0
+	}
+
+	log.Debugf("[latency] current %d running %d budget %d -> delta %d", current, t.running, t.budget, delta)
+	return delta
+}
+
+// SetPoint returns the latency budget
+func (t *LatencyTarget) SetPoint() int {
+	return t.budget
+}
+
+// Reconfigure takes on the budget from another latency target
+func (t *LatencyTarget) Reconfigure(latest Target) bool {
+	l, ok := latest.(*LatencyTarget)
+	if !ok {
+		return false
+	}
+
+	t.budget = l.budget
+	return true
+}
@@ -0,0 +1,79 @@
+package target
+
+import (
+	"testing"
+)
+
+func TestLatency(t *testing.T) {
+	l := NewLatencyTarget(200)
+
+	if !l.Meeting(200) || l.Meeting(201) {
+		t.Fatalf("Should meet the target within the budget")
+	}
+
+	if !l.Exceeding(99) || l.Exceeding(100) {
+		t.Fatalf("Should only exceed the target with plenty of headroom")
+	}
+
+	// Nothing running, so just start one
+	if d := l.Delta(500); d != 1 {
+		t.Fatalf("Expected delta 1 with nothing running but was %d", d)
+	}
+
+	// Double the budget means double the containers
+	l.Observe(4)
+	if d := l.Delta(400); d != 4 {
+		t.Fatalf("Expected delta 4 but was %d", d)
+	}
+
+	// Only just over still adds a container
+	if d := l.Delta(201); d != 1 {
+		t.Fatalf("Expected delta 1 but was %d", d)
+	}
+
+	// Within budget but not much headroom
+	if d := l.Delta(150); d != 0 {
+		t.Fatalf("Expected delta 0 but was %d", d)
+	}
+
+	// Scale down one at a time, once we've had headroom for long enough
+	for i := 1; i < latencyScaleDownSamples; i++ {
+		if d := l.Delta(50); d != 0 {
+			t.Fatalf("Scaled down too soon after %d samples", i)
+		}
+	}
+
+	if d := l.Delta(50); d != -1 {
+		t.Fatalf("Expected delta -1 but was %d", d)
+	}
+
+	if d := l.Delta(50); d != 0 {
+		t.Fatalf("Should wait for headroom again before scaling down again, but delta was %d", d)
+	}
+
+	// Breaking the budget starts the wait again
+	for i := 1; i < latencyScaleDownSamples; i++ {
+		l.Delta(50)
+	}
+	l.Delta(300)
+	if d := l.Delta(50); d != 0 {
+		t.Fatalf("Expected delta 0 after breaking the budget but was %d", d)
+	}
+}
+
+func TestLatencyReconfigure(t *testing.T) {
+	l := NewLatencyTarget(200)
+	l.Observe(3)
+
+	if !l.Reconfigure(NewLatencyTarget(100)) {
+		t.Fatalf("Should be able to reconfigure with another latency target")
+	}
+
+	if l.SetPoint() != 100 || l.running != 3 {
+		t.Fatalf("Expected budget 100 with 3 running but was %d with %d", l.SetPoint(), l.running)
+	}
+
+	if l.Reconfigure(NewUtilizationTarget(10)) {
+		t.Fatalf("Shouldn't be able to reconfigure with a different target type")
+	}
+}
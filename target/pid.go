@@ -0,0 +1,90 @@
+package target
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/microscaling/microscaling/utils"
+)
+
+// PIDConfig holds the controller settings for a queue length target. Anything that isn't set (nil) falls
+// back to the MSS_KP, MSS_KI, MSS_KD, MSS_KU, MSS_TU and MSS_VEL_SAMPLES environment variables, and then to
+// our built-in defaults, so each task only needs to set the values it wants to change.
+type PIDConfig struct {
+	KP         *float64 `json:"kp,omitempty"`
+	KI         *float64 `json:"ki,omitempty"`
+	KD         *float64 `json:"kd,omitempty"`
+	KU         *float64 `json:"ku,omitempty"` // ultimate gain, used to derive the default kP
+	TU         *float64 `json:"tu,omitempty"` // oscillation period, used to derive the default kD
+	VelSamples *int     `json:"velSamples,omitempty"`
+}
+
+// Tunable is implemented by targets that use a PID controller
+type Tunable interface {
+	Tune(pid PIDConfig)
+}
+
+// IsZero returns true if nothing is set
+func (c PIDConfig) IsZero() bool {
+	return c == PIDConfig{}
+}
+
+// Merge returns these settings overridden by anything that's set in overrides
+func (c PIDConfig) Merge(overrides PIDConfig) PIDConfig {
+	if overrides.KP != nil {
+		c.KP = overrides.KP
+	}
+	if overrides.KI != nil {
+		c.KI = overrides.KI
+	}
+	if overrides.KD != nil {
+		c.KD = overrides.KD
+	}
+	if overrides.KU != nil {
+		c.KU = overrides.KU
+	}
+	if overrides.TU != nil {
+		c.TU = overrides.TU
+	}
+	if overrides.VelSamples != nil {
+		c.VelSamples = overrides.VelSamples
+	}
+
+	return c
+}
+
+// gains works out the controller parameters, filling in anything that isn't set from the environment or defaults
+func (c PIDConfig) gains() (kP float64, kI float64, kD float64, velSamples int) {
+	// TODO!! Better ways to calculate these heuristics
+	kU := floatOrEnv(c.KU, "MSS_KU", 0.05)
+	tU := floatOrEnv(c.TU, "MSS_TU", 10.0)
+
+	// Ziegler-Nichols PID
+	// kP := 0.6 * kU
+	// kI := kP * 2.0 / tU
+	// kD := kP * tU / 8.0
+
+	// Ziegler-Nichols PD
+	kD = floatOrEnv(c.KD, "MSS_KD", float64(tU/8.0))
+	kP = floatOrEnv(c.KP, "MSS_KP", float64(0.8*kU))
+	kI = floatOrEnv(c.KI, "MSS_KI", float64(0))
+
+	if c.VelSamples != nil && *c.VelSamples > 0 {
+		velSamples = *c.VelSamples
+	} else {
+		velSamples, _ = strconv.Atoi(os.Getenv("MSS_VEL_SAMPLES"))
+		if velSamples <= 0 {
+			velSamples = queueAverageSamples
+		}
+	}
+
+	return
+}
+
+func floatOrEnv(v *float64, envVar string, defaultVal float64) float64 {
+	if v != nil {
+		return *v
+	}
+
+	return utils.EnvFl64(envVar, defaultVal)
+}
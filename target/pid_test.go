@@ -0,0 +1,113 @@
+package target
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+func nearly(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestPIDGains(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		pid        PIDConfig
+		kP         float64
+		kI         float64
+		kD         float64
+		velSamples int
+	}{
+		{
+			name:       "defaults",
+			kP:         0.04,
+			kI:         0,
+			kD:         1.25,
+			velSamples: 1,
+		},
+		{
+			name:       "env",
+			env:        map[string]string{"MSS_KP": "0.5", "MSS_KI": "0.1", "MSS_TU": "16", "MSS_VEL_SAMPLES": "3"},
+			kP:         0.5,
+			kI:         0.1,
+			kD:         2,
+			velSamples: 3,
+		},
+		{
+			name:       "task overrides env",
+			env:        map[string]string{"MSS_KP": "0.5", "MSS_KI": "0.1", "MSS_VEL_SAMPLES": "3"},
+			pid:        PIDConfig{KP: floatPtr(2), KD: floatPtr(0.5), VelSamples: intPtr(5)},
+			kP:         2,
+			kI:         0.1,
+			kD:         0.5,
+			velSamples: 5,
+		},
+		{
+			name:       "derived from task ku and tu",
+			env:        map[string]string{"MSS_KU": "1", "MSS_TU": "1"},
+			pid:        PIDConfig{KU: floatPtr(0.5), TU: floatPtr(24)},
+			kP:         0.4,
+			kD:         3,
+			velSamples: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		for k, v := range tc.env {
+			os.Setenv(k, v)
+		}
+
+		kP, kI, kD, velSamples := tc.pid.gains()
+		if !nearly(kP, tc.kP) || !nearly(kI, tc.kI) || !nearly(kD, tc.kD) || velSamples != tc.velSamples {
+			t.Errorf("%s: expected %f %f %f %d but got %f %f %f %d", tc.name, tc.kP, tc.kI, tc.kD, tc.velSamples, kP, kI, kD, velSamples)
+		}
+
+		for k := range tc.env {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestPIDMerge(t *testing.T) {
+	c := PIDConfig{KP: floatPtr(1), KI: floatPtr(2)}
+	m := c.Merge(PIDConfig{KI: floatPtr(3), VelSamples: intPtr(4)})
+
+	if *m.KP != 1 || *m.KI != 3 || *m.VelSamples != 4 || m.KD != nil {
+		t.Fatalf("Bad merge %v", m)
+	}
+
+	if !(PIDConfig{}).IsZero() || m.IsZero() {
+		t.Fatalf("Bad IsZero")
+	}
+}
+
+func TestQueueTune(t *testing.T) {
+	q := NewTunedQueueLengthTarget(10, PIDConfig{KP: floatPtr(1), KD: floatPtr(1)})
+	q.Delta(20)
+	q.Delta(30)
+
+	// Tuning keeps settings that aren't overridden
+	q.Tune(PIDConfig{KI: floatPtr(0.5)})
+	if q.kP != 1 || q.kI != 0.5 || q.kD != 1 {
+		t.Fatalf("Bad gains after tuning: %f %f %f", q.kP, q.kI, q.kD)
+	}
+
+	if q.startCount == 0 {
+		t.Fatalf("Velocity history shouldn't be reset if the samples haven't changed")
+	}
+
+	q.Tune(PIDConfig{VelSamples: intPtr(3)})
+	if q.vel.samples != 3 || len(q.vel.changes) != 4 || q.startCount != 0 {
+		t.Fatalf("Velocity history should be reset")
+	}
+}
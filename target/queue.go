@@ -2,10 +2,6 @@ package target
 
 import (
 	"math"
-	"os"
-	"strconv"
-
-	"github.com/microscaling/microscaling/utils"
 )
 
 // QueueLengthTarget is where we ant to keep the number of items in a queue under a certain length
@@ -13,54 +9,85 @@ type QueueLengthTarget struct {
 	length     int
 	minLength  int
 	lastLength int
-	vel        []int
-	velSamples int
+	vel        queueVelocity
 	cumErr     int
+	lastErr    int
+	prevCumErr int
 	kP         float64
 	kI         float64
 	kD         float64
+	pid        PIDConfig
 	startCount int
 	useIFactor bool
+	running    int
+	name       string
+	tuner      *relayTuner
+	tuned      bool
 }
 
+// compile-time assert that we implement the right interfaces
+var _ Target = (*QueueLengthTarget)(nil)
+var _ Tunable = (*QueueLengthTarget)(nil)
+var _ Observer = (*QueueLengthTarget)(nil)
+var _ Feedback = (*QueueLengthTarget)(nil)
+
 const queueLengthExceedingPercent float64 = 0.7
 const queueAverageSamples int = 1
+const constMaxCumErrFactor int = 10 // limit the cumulative error to this many times the target length
 
-// NewQueueLengthTarget creates a new target for queues
+// NewQueueLengthTarget creates a new target for queues, with the controller settings from the environment
 func NewQueueLengthTarget(length int) *QueueLengthTarget {
-	// TODO!! Better ways to calculate these heuristics and/or pass them in
-	kU := utils.EnvFl64("MSS_KU", 0.05)
-	tU := utils.EnvFl64("MSS_TU", 10.0)
-
-	// Ziegler-Nichols PID
-	// kP := 0.6 * kU
-	// kI := kP * 2.0 / tU
-	// kD := kP * tU / 8.0
+	return NewTunedQueueLengthTarget(length, PIDConfig{})
+}
 
-	// Ziegler-Nichols PD
-	kD := utils.EnvFl64("MSS_KD", float64(tU/8.0))
-	kP := utils.EnvFl64("MSS_KP", float64(0.8*kU))
-	kI := utils.EnvFl64("MSS_KI", float64(0))
+// NewTunedQueueLengthTarget creates a new target for queues with its own controller settings
+func NewTunedQueueLengthTarget(length int, pid PIDConfig) *QueueLengthTarget {
+	t := &QueueLengthTarget{
+		length:     length,
+		minLength:  int(float64(length) * queueLengthExceedingPercent),
+		useIFactor: false,
+	}
 
-	log.Debugf("[new ql] kP = %f, kI = %f, kD = %f", kP, kI, kD)
+	t.Tune(pid)
+	return t
+}
 
+// Tune changes some or all of the controller settings
+func (t *QueueLengthTarget) Tune(pid PIDConfig) {
 	var velSamples int
-	velSamplesStr := os.Getenv("MSS_VEL_SAMPLES")
-	velSamples, err := strconv.Atoi(velSamplesStr)
-	if err != nil || velSamples == 0 {
-		velSamples = queueAverageSamples
+
+	t.pid = t.pid.Merge(pid)
+	t.kP, t.kI, t.kD, velSamples = t.pid.gains()
+	log.Debugf("[ql] tuned: kP = %f, kI = %f, kD = %f", t.kP, t.kI, t.kD)
+
+	// The velocity history can't be kept if we're now averaging over a different number of samples
+	if velSamples != t.vel.samples {
+		t.vel = newQueueVelocity(velSamples)
+		t.startCount = 0
 	}
+}
 
-	return &QueueLengthTarget{
-		length:     length,
-		minLength:  int(float64(length) * queueLengthExceedingPercent),
-		kP:         kP,
-		kI:         kI,
-		kD:         kD,
-		vel:        make([]int, velSamples+1),
-		velSamples: velSamples,
-		useIFactor: false,
+// AutoTune works out the gains for this queue with a relay test, unless we've already saved gains for this task.
+// While we're tuning we move relayStep containers above and below the number that were running when we started.
+func (t *QueueLengthTarget) AutoTune(name string, relayStep int) {
+	t.name = name
+
+	if tuningStore != nil {
+		if pid, ok := tuningStore.Load(name); ok {
+			log.Infof("[ql] using saved gains for %s", name)
+			t.Tune(pid)
+			t.tuned = true
+			return
+		}
 	}
+
+	log.Infof("[ql] auto-tuning %s", name)
+	t.tuner = newRelayTuner(relayStep)
+}
+
+// Observe tells us how many containers are running
+func (t *QueueLengthTarget) Observe(running int) {
+	t.running = running
 }
 
 // Meeting returns true if the target is currently met
@@ -86,8 +113,14 @@ func (t *QueueLengthTarget) Delta(currentLength int) (delta int) {
 	var deltafloat float64
 	var currErr int
 
+	if t.tuner != nil {
+		return t.tuningDelta(currentLength)
+	}
+
 	currErr = currentLength - t.length
+	t.prevCumErr = t.cumErr
 	t.cumErr = t.cumErr + currErr
+	t.lastErr = currErr
 
 	// We only start using kI once we have hit or passed the target, to prevent it being unnecessarily large if we start
 	// with lots of items on the queue
@@ -101,29 +134,21 @@ func (t *QueueLengthTarget) Delta(currentLength int) (delta int) {
 		}
 	}
 
-	var aveVel float64
-	// Store the new value at the end of the array (this is one more than we need), but only average over the right number of samples
-	t.vel[t.velSamples] = currentLength - t.lastLength
-	for i := 0; i <= t.velSamples-1; i++ {
-		t.vel[i] = t.vel[i+1]
-		aveVel = aveVel + float64(t.vel[i])
-	}
-
-	aveVel = aveVel / float64(t.velSamples)
+	aveVel := t.vel.add(float64(currentLength - t.lastLength))
 
 	// There is a point beyond which there is no point letting cumErr grow, because our max containers can't
 	// necessarily keep up (and also a question of symmetry, since a queue length can't go below 0?)
-	// if t.cumErr > 10*t.length {
-	// 	t.cumErr = 10 * t.length
-	// }
-	// if t.cumErr < -10*t.length {
-	// 	t.cumErr = -10 * t.length
-	// }
+	if t.cumErr > constMaxCumErrFactor*t.length {
+		t.cumErr = constMaxCumErrFactor * t.length
+	}
+	if t.cumErr < -constMaxCumErrFactor*t.length {
+		t.cumErr = -constMaxCumErrFactor * t.length
+	}
 
 	t.lastLength = currentLength
 
 	// To start with, velocity isn't valid
-	if t.startCount < t.velSamples {
+	if t.startCount < t.vel.samples {
 		log.Debugf("[ql] err %d, cumErr %d", currErr, t.cumErr)
 		log.Debugf("[ql] err * kp %f, cumErr * kI %f", t.kP*float64(currErr), kI*float64(t.cumErr))
 		deltafloat = t.kP*float64(currErr) + kI*float64(t.cumErr)
@@ -141,3 +166,105 @@ func (t *QueueLengthTarget) Delta(currentLength int) (delta int) {
 	log.Debugf("[ql] => delta %d", delta)
 	return
 }
+
+// SetPoint returns the queue length we're aiming for
+func (t *QueueLengthTarget) SetPoint() int {
+	return t.length
+}
+
+// Applied tells us how much of the delta we asked for was actually applied. If we were held back we stop
+// adding to the cumulative error, otherwise it keeps growing and we overshoot when there's space again.
+func (t *QueueLengthTarget) Applied(requested int, applied int) {
+	if t.tuner != nil {
+		return
+	}
+
+	heldBackUp := requested > 0 && applied < requested && t.lastErr > 0
+	heldBackDown := requested < 0 && applied > requested && t.lastErr < 0
+	if heldBackUp || heldBackDown {
+		log.Debugf("[ql] applied %d of %d, not accumulating err %d", applied, requested, t.lastErr)
+		t.cumErr = t.prevCumErr
+		t.lastErr = 0
+	}
+}
+
+// tuningDelta follows the relay test, and switches over to the controller with the new gains when we're done
+func (t *QueueLengthTarget) tuningDelta(currentLength int) (delta int) {
+	want, done := t.tuner.update(t.running, currentLength, t.length, t.minLength)
+	delta = want - t.running
+	t.lastLength = currentLength
+
+	log.Debugf("[ql] tuning %s: want %d containers, delta %d", t.name, want, delta)
+	if done {
+		t.finishTuning()
+	}
+
+	return delta
+}
+
+func (t *QueueLengthTarget) finishTuning() {
+	kU, tU, ok := t.tuner.result(t.length, t.minLength)
+	t.tuner = nil
+	if !ok {
+		log.Errorf("[ql] couldn't tune %s as the queue didn't oscillate enough, keeping kP = %f, kI = %f, kD = %f", t.name, t.kP, t.kI, t.kD)
+		return
+	}
+
+	// Ziegler-Nichols "some overshoot" gains. The classic rule is too aggressive for queues, as containers take a while
+	// to start and we can only scale by whole containers, so it keeps the queue oscillating.
+	kP := kU / 3.0
+	kI := 0.0
+	kD := kP * tU / 3.0
+
+	pid := PIDConfig{KP: &kP, KI: &kI, KD: &kD, KU: &kU, TU: &tU}
+	t.Tune(pid)
+	t.tuned = true
+
+	// Start the controller afresh
+	t.cumErr = 0
+	t.startCount = 0
+	t.useIFactor = false
+	log.Infof("[ql] tuned %s: kU = %f, tU = %f", t.name, kU, tU)
+
+	if tuningStore != nil {
+		err := tuningStore.Save(t.name, pid)
+		if err != nil {
+			log.Errorf("[ql] failed to save gains for %s: %v", t.name, err)
+		}
+	}
+}
+
+// Reconfigure takes on the length and controller parameters from another queue length target, keeping
+// the error and velocity history we have already built up.
+func (t *QueueLengthTarget) Reconfigure(latest Target) bool {
+	l, ok := latest.(*QueueLengthTarget)
+	if !ok {
+		return false
+	}
+
+	t.length = l.length
+	t.minLength = l.minLength
+	t.name = l.name
+
+	if l.tuner != nil && (t.tuner != nil || t.tuned) {
+		// Keep the gains we're working out, or have already worked out
+		log.Debugf("[ql] reconfigured: length %d, keeping auto-tuned gains", t.length)
+		return true
+	}
+
+	t.tuner = l.tuner
+	t.tuned = l.tuned
+	t.kP = l.kP
+	t.kI = l.kI
+	t.kD = l.kD
+	t.pid = l.pid
+
+	// The velocity history can't be kept if we're now averaging over a different number of samples
+	if l.vel.samples != t.vel.samples {
+		t.vel = l.vel
+		t.startCount = 0
+	}
+
+	log.Debugf("[ql] reconfigured: length %d, kP = %f, kI = %f, kD = %f", t.length, t.kP, t.kI, t.kD)
+	return true
+}
@@ -17,7 +17,7 @@ func TestNewQueueTarget(t *testing.T) {
 		t.Fatalf("Wrong minLength")
 	}
 
-	if q.velSamples != 1 {
+	if q.vel.samples != 1 {
 		t.Fatalf("Wrong velocity samples")
 	}
 }
@@ -88,3 +88,64 @@ func TestQueue(t *testing.T) {
 		t.Fatalf("Bad delta (3)")
 	}
 }
+
+func TestQueueReconfigure(t *testing.T) {
+	q := NewQueueLengthTarget(10)
+	q.Delta(20)
+	q.Delta(30)
+
+	if !q.Reconfigure(NewQueueLengthTarget(50)) {
+		t.Fatalf("Should be able to reconfigure with another queue target")
+	}
+
+	if q.length != 50 || q.minLength != 35 {
+		t.Fatalf("Length not updated")
+	}
+
+	if q.cumErr != 30 || q.lastLength != 30 {
+		t.Fatalf("Controller state should have been kept")
+	}
+
+	if q.Reconfigure(NewSimpleQueueLengthTarget(50)) {
+		t.Fatalf("Shouldn't be able to reconfigure with a different target type")
+	}
+}
+
+func TestQueueAntiWindup(t *testing.T) {
+	q := NewQueueLengthTarget(10)
+	q.kP = 0
+	q.kD = 0
+	q.kI = 0.1
+	q.useIFactor = true
+
+	// Err = 20, and we got everything we asked for
+	d := q.Delta(30)
+	q.Applied(d, d)
+	if q.cumErr != 20 {
+		t.Fatalf("Expected cumulative error 20 but was %d", q.cumErr)
+	}
+
+	// Capped, so the error shouldn't keep accumulating
+	for i := 0; i < 5; i++ {
+		d = q.Delta(30)
+		q.Applied(d, 0)
+	}
+	if q.cumErr != 20 {
+		t.Fatalf("Expected cumulative error to stay at 20 while capped but was %d", q.cumErr)
+	}
+
+	// Below the target the error comes back down
+	d = q.Delta(0)
+	q.Applied(d, d)
+	if q.cumErr != 10 {
+		t.Fatalf("Expected cumulative error 10 but was %d", q.cumErr)
+	}
+
+	// Even without feedback the cumulative error is limited
+	for i := 0; i < 50; i++ {
+		q.Delta(30)
+	}
+	if q.cumErr != constMaxCumErrFactor*10 {
+		t.Fatalf("Expected cumulative error to be limited to %d but was %d", constMaxCumErrFactor*10, q.cumErr)
+	}
+}
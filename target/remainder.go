@@ -29,3 +29,14 @@ func (t *RemainderTarget) Delta(current int) (delta int) {
 	delta = t.maxContainers
 	return
 }
+
+// Reconfigure takes on the max containers from another remainder target
+func (t *RemainderTarget) Reconfigure(latest Target) bool {
+	l, ok := latest.(*RemainderTarget)
+	if !ok {
+		return false
+	}
+
+	t.maxContainers = l.maxContainers
+	return true
+}
@@ -23,3 +23,14 @@ func TestRemainder(t *testing.T) {
 		t.Fatalf("Remainder delta should always be maxContainers")
 	}
 }
+
+func TestRemainderReconfigure(t *testing.T) {
+	r := NewRemainderTarget(100)
+	if !r.Reconfigure(NewRemainderTarget(5)) || r.maxContainers != 5 {
+		t.Fatalf("Max containers not reconfigured")
+	}
+
+	if r.Reconfigure(NewQueueLengthTarget(5)) {
+		t.Fatalf("Shouldn't be able to reconfigure with a different target type")
+	}
+}
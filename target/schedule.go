@@ -0,0 +1,72 @@
+package target
+
+import (
+	"time"
+
+	"github.com/microscaling/microscaling/schedule"
+)
+
+// ScheduleTarget follows a calendar rather than a metric. We run the number of containers set by whichever
+// schedule windows are open, and none (so the task's min containers) otherwise.
+type ScheduleTarget struct {
+	schedule *schedule.Schedule
+	running  int
+	desired  int
+}
+
+// compile-time assert that we implement the right interfaces
+var _ Target = (*ScheduleTarget)(nil)
+var _ Observer = (*ScheduleTarget)(nil)
+var _ Timed = (*ScheduleTarget)(nil)
+var _ Reconfigurable = (*ScheduleTarget)(nil)
+
+// NewScheduleTarget creates a new target that follows the schedule
+func NewScheduleTarget(s *schedule.Schedule) *ScheduleTarget {
+	return &ScheduleTarget{
+		schedule: s,
+	}
+}
+
+// Observe tells us how many containers are running
+func (t *ScheduleTarget) Observe(running int) {
+	t.running = running
+}
+
+// At works out how many containers we want at this time
+func (t *ScheduleTarget) At(now time.Time) {
+	desired := 0
+	if o := t.schedule.Active(now); o.Containers != nil {
+		desired = *o.Containers
+	}
+
+	if desired != t.desired {
+		log.Debugf("[schedule] now want %d containers", desired)
+	}
+	t.desired = desired
+}
+
+// Meeting returns true if we have at least as many containers as the schedule wants. The metric isn't used.
+func (t *ScheduleTarget) Meeting(current int) bool {
+	return t.running >= t.desired
+}
+
+// Exceeding returns true if we have more containers than the schedule wants
+func (t *ScheduleTarget) Exceeding(current int) bool {
+	return t.running > t.desired
+}
+
+// Delta returns the number of containers to add (remove if negative) to match the schedule
+func (t *ScheduleTarget) Delta(current int) int {
+	return t.desired - t.running
+}
+
+// Reconfigure takes on the schedule from another schedule target
+func (t *ScheduleTarget) Reconfigure(latest Target) bool {
+	l, ok := latest.(*ScheduleTarget)
+	if !ok {
+		return false
+	}
+
+	t.schedule = l.schedule
+	return true
+}
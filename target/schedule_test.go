@@ -0,0 +1,37 @@
+package target
+
+import (
+	"testing"
+	"time"
+
+	"github.com/microscaling/microscaling/schedule"
+)
+
+func TestScheduleTarget(t *testing.T) {
+	three := 3
+	s, err := schedule.New([]schedule.WindowConfig{
+		{Cron: "0 9 * * *", Duration: "8h", Timezone: "UTC", Containers: &three},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	st := NewScheduleTarget(s)
+	morning := time.Date(2017, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	st.Observe(1)
+	st.At(morning)
+	if st.Meeting(0) || st.Exceeding(0) || st.Delta(0) != 2 {
+		t.Fatalf("Expected to scale up by 2 during the window")
+	}
+
+	st.Observe(3)
+	if !st.Meeting(0) || st.Exceeding(0) || st.Delta(0) != 0 {
+		t.Fatalf("Expected to stay at 3 during the window")
+	}
+
+	st.At(morning.Add(8 * time.Hour))
+	if !st.Meeting(0) || !st.Exceeding(0) || st.Delta(0) != -3 {
+		t.Fatalf("Expected to scale down by 3 after the window")
+	}
+}
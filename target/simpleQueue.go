@@ -49,3 +49,8 @@ func (t *SimpleQueueLengthTarget) Delta(currentLength int) (delta int) {
 	log.Debugf("[sql] delta %d", delta)
 	return
 }
+
+// SetPoint returns the queue length we're aiming for
+func (t *SimpleQueueLengthTarget) SetPoint() int {
+	return t.length
+}
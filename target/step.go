@@ -0,0 +1,154 @@
+package target
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Step is a range of metric values and the number of containers to add (or remove if negative) when the metric
+// is in that range. The range includes Lower but not Upper, and there's no upper bound if Upper isn't set.
+type Step struct {
+	Lower int  `json:"lower"`
+	Upper *int `json:"upper,omitempty"`
+	Delta int  `json:"delta"`
+}
+
+// StepTarget scales by a fixed number of containers depending on which range the metric is in, like the step
+// scaling policies in cloud autoscalers. It's easier to reason about than a PID controller, but you need to
+// pick the steps yourself.
+type StepTarget struct {
+	steps []Step
+}
+
+// compile-time assert that we implement the right interfaces
+var _ Target = (*StepTarget)(nil)
+var _ Reconfigurable = (*StepTarget)(nil)
+
+// NewStepTarget creates a new target from the steps, which should have been checked with ValidateSteps
+func NewStepTarget(steps []Step) *StepTarget {
+	sorted := make([]Step, len(steps))
+	copy(sorted, steps)
+	sort.Sort(byLower(sorted))
+
+	return &StepTarget{
+		steps: sorted,
+	}
+}
+
+type byLower []Step
+
+func (s byLower) Len() int           { return len(s) }
+func (s byLower) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byLower) Less(i, j int) bool { return s[i].Lower < s[j].Lower }
+
+// ValidateSteps checks that every step has a range, and that the ranges don't overlap
+func ValidateSteps(steps []Step) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("At least one step is needed")
+	}
+
+	sorted := NewStepTarget(steps).steps
+	for i, s := range sorted {
+		if s.Upper != nil && *s.Upper <= s.Lower {
+			return fmt.Errorf("Step %s has an upper bound that isn't above its lower bound", s)
+		}
+
+		if i > 0 {
+			prev := sorted[i-1]
+			if prev.Upper == nil || *prev.Upper > s.Lower {
+				return fmt.Errorf("Steps %s and %s overlap", prev, s)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ParseSteps reads steps written like "0-100:-1,100-1000:2,1000-:5", as we use in labels
+func ParseSteps(s string) (steps []Step, err error) {
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Step %q should be range:delta", part)
+		}
+
+		bounds := strings.Split(fields[0], "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("Step %q should have a range like 100-1000, or 1000- with no upper bound", part)
+		}
+
+		var step Step
+		step.Lower, err = strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("Bad lower bound in step %q", part)
+		}
+
+		if bounds[1] != "" {
+			upper, err := strconv.Atoi(bounds[1])
+			if err != nil {
+				return nil, fmt.Errorf("Bad upper bound in step %q", part)
+			}
+			step.Upper = &upper
+		}
+
+		step.Delta, err = strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("Bad delta in step %q", part)
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps, ValidateSteps(steps)
+}
+
+// String writes the step in the same way as ParseSteps reads it
+func (s Step) String() string {
+	upper := ""
+	if s.Upper != nil {
+		upper = strconv.Itoa(*s.Upper)
+	}
+
+	return fmt.Sprintf("%d-%s:%d", s.Lower, upper, s.Delta)
+}
+
+// step returns the delta for the range the metric is in, or 0 if it isn't in any of them
+func (t *StepTarget) step(current int) int {
+	for _, s := range t.steps {
+		if current >= s.Lower && (s.Upper == nil || current < *s.Upper) {
+			return s.Delta
+		}
+	}
+
+	return 0
+}
+
+// Meeting returns true unless the metric is in a range that adds containers
+func (t *StepTarget) Meeting(current int) bool {
+	return t.step(current) <= 0
+}
+
+// Exceeding returns true if the metric is in a range that removes containers
+func (t *StepTarget) Exceeding(current int) bool {
+	return t.step(current) < 0
+}
+
+// Delta returns the number of containers to add (remove if negative) for the range the metric is in
+func (t *StepTarget) Delta(current int) int {
+	delta := t.step(current)
+	log.Debugf("[step] current %d -> delta %d", current, delta)
+	return delta
+}
+
+// Reconfigure takes on the steps from another step target
+func (t *StepTarget) Reconfigure(latest Target) bool {
+	l, ok := latest.(*StepTarget)
+	if !ok {
+		return false
+	}
+
+	t.steps = l.steps
+	return true
+}
@@ -0,0 +1,84 @@
+package target
+
+import (
+	"testing"
+)
+
+func TestStep(t *testing.T) {
+	steps, err := ParseSteps("100-1000:2, 0-100:-1,1000-:5")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	st := NewStepTarget(steps)
+
+	tests := []struct {
+		current   int
+		meeting   bool
+		exceeding bool
+		delta     int
+	}{
+		{current: -1, meeting: true, exceeding: false, delta: 0},
+		{current: 0, meeting: true, exceeding: true, delta: -1},
+		{current: 99, meeting: true, exceeding: true, delta: -1},
+		{current: 100, meeting: false, exceeding: false, delta: 2},
+		{current: 1000, meeting: false, exceeding: false, delta: 5},
+		{current: 1000000, meeting: false, exceeding: false, delta: 5},
+	}
+
+	for _, tc := range tests {
+		if st.Meeting(tc.current) != tc.meeting {
+			t.Errorf("%d: expected meeting %t", tc.current, tc.meeting)
+		}
+
+		if st.Exceeding(tc.current) != tc.exceeding {
+			t.Errorf("%d: expected exceeding %t", tc.current, tc.exceeding)
+		}
+
+		if d := st.Delta(tc.current); d != tc.delta {
+			t.Errorf("%d: expected delta %d but was %d", tc.current, tc.delta, d)
+		}
+	}
+
+	if st.steps[2].String() != "1000-:5" {
+		t.Errorf("Unexpected string for step %s", st.steps[2])
+	}
+}
+
+func TestParseStepsErrors(t *testing.T) {
+	bad := []string{
+		"",
+		"0-100",
+		"0:1",
+		"a-100:1",
+		"0-b:1",
+		"0-100:c",
+		"100-0:1",
+		"0-100:-1,50-:2",
+		"0-:-1,100-:2",
+	}
+
+	for _, s := range bad {
+		if _, err := ParseSteps(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
+
+func TestStepReconfigure(t *testing.T) {
+	steps, _ := ParseSteps("0-10:-1,10-:1")
+	st := NewStepTarget(steps)
+
+	latest, _ := ParseSteps("0-50:-1,50-:3")
+	if !st.Reconfigure(NewStepTarget(latest)) {
+		t.Fatalf("Should be able to reconfigure with another step target")
+	}
+
+	if st.Delta(60) != 3 {
+		t.Fatalf("Expected the new steps")
+	}
+
+	if st.Reconfigure(NewRemainderTarget(10)) {
+		t.Fatalf("Shouldn't be able to reconfigure with a different target type")
+	}
+}
@@ -0,0 +1,103 @@
+package target
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// TuningStore saves the controller settings we worked out for each task, so we don't have to tune them
+// again when we restart
+type TuningStore interface {
+	Load(name string) (pid PIDConfig, ok bool)
+	Save(name string, pid PIDConfig) error
+}
+
+// tuningStore is where auto-tuned targets save their settings. If it's nil, tuned settings only last until we restart.
+var tuningStore TuningStore
+
+// SetTuningStore sets where auto-tuned targets save their settings
+func SetTuningStore(store TuningStore) {
+	tuningStore = store
+}
+
+// FileTuningStore keeps the settings for all tasks in a JSON file
+type FileTuningStore struct {
+	FilePath string
+	sync.Mutex
+}
+
+// compile-time assert that we implement the right interface
+var _ TuningStore = (*FileTuningStore)(nil)
+
+// NewFileTuningStore gets a new FileTuningStore
+func NewFileTuningStore(filePath string) *FileTuningStore {
+	return &FileTuningStore{
+		FilePath: filePath,
+	}
+}
+
+// Load gets the saved settings for a task
+func (f *FileTuningStore) Load(name string) (pid PIDConfig, ok bool) {
+	f.Lock()
+	defer f.Unlock()
+
+	saved, err := f.read()
+	if err != nil {
+		log.Errorf("Failed to read tuning file %s: %v", f.FilePath, err)
+		return pid, false
+	}
+
+	pid, ok = saved[name]
+	return pid, ok
+}
+
+// Save stores the settings for a task, keeping the settings for other tasks
+func (f *FileTuningStore) Save(name string, pid PIDConfig) error {
+	f.Lock()
+	defer f.Unlock()
+
+	saved, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	saved[name] = pid
+	b, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename it, so we never leave a half-written file behind
+	tmp, err := ioutil.TempFile(filepath.Dir(f.FilePath), ".microscaling-tuning")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(b)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.FilePath)
+}
+
+// read gets all the saved settings. It's fine for the file not to exist yet.
+func (f *FileTuningStore) read() (saved map[string]PIDConfig, err error) {
+	saved = make(map[string]PIDConfig)
+
+	b, err := ioutil.ReadFile(f.FilePath)
+	if os.IsNotExist(err) {
+		return saved, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, &saved)
+	return saved, err
+}
@@ -0,0 +1,99 @@
+package target
+
+import (
+	"math"
+)
+
+// UtilizationTarget aims to keep the metric divided by the number of running containers near a set point,
+// for example 100 messages per consumer or 70% CPU. This assumes the work is shared evenly across
+// containers, so we can work out how many we need directly rather than tuning a controller.
+type UtilizationTarget struct {
+	setPoint float64
+	running  int
+}
+
+// compile-time assert that we implement the right interfaces
+var _ Target = (*UtilizationTarget)(nil)
+var _ Observer = (*UtilizationTarget)(nil)
+var _ Reconfigurable = (*UtilizationTarget)(nil)
+var _ SetPointer = (*UtilizationTarget)(nil)
+
+// We don't scale while the value per container is within this fraction of the set point, so that we don't
+// keep adding and removing a container when we're close to it
+const utilizationTolerance float64 = 0.1
+
+// NewUtilizationTarget creates a new target that tracks the metric value per container
+func NewUtilizationTarget(setPoint float64) *UtilizationTarget {
+	return &UtilizationTarget{
+		setPoint: setPoint,
+	}
+}
+
+// Observe tells us how many containers are running
+func (t *UtilizationTarget) Observe(running int) {
+	t.running = running
+}
+
+// perContainer is the metric value for each running container. With nothing running, any work at all is
+// more than we can handle.
+func (t *UtilizationTarget) perContainer(current int) float64 {
+	if t.running == 0 {
+		if current > 0 {
+			return math.Inf(1)
+		}
+		return 0
+	}
+
+	return float64(current) / float64(t.running)
+}
+
+// Meeting returns true if the value per container isn't too far above the set point
+func (t *UtilizationTarget) Meeting(current int) bool {
+	meeting := t.perContainer(current) <= t.setPoint*(1+utilizationTolerance)
+	if !meeting {
+		log.Debugf("[util] not meeting: current %d running %d target %f", current, t.running, t.setPoint)
+	}
+	return meeting
+}
+
+// Exceeding returns true if the value per container is far enough below the set point that we could use fewer
+func (t *UtilizationTarget) Exceeding(current int) bool {
+	if t.running == 0 {
+		return false
+	}
+
+	exceeding := t.perContainer(current) < t.setPoint*(1-utilizationTolerance)
+	if exceeding {
+		log.Debugf("[util] exceeding: current %d running %d target %f", current, t.running, t.setPoint)
+	}
+	return exceeding
+}
+
+// Delta returns the number of containers to add (remove if negative) to bring the value per container to the set point
+func (t *UtilizationTarget) Delta(current int) (delta int) {
+	if t.setPoint <= 0 || (t.Meeting(current) && !t.Exceeding(current)) {
+		return 0
+	}
+
+	desired := int(math.Ceil(float64(current) / t.setPoint))
+	delta = desired - t.running
+
+	log.Debugf("[util] current %d running %d target %f -> delta %d", current, t.running, t.setPoint, delta)
+	return delta
+}
+
+// SetPoint returns the value per container we're aiming for
+func (t *UtilizationTarget) SetPoint() int {
+	return int(t.setPoint + 0.5)
+}
+
+// Reconfigure takes on the set point from another utilization target
+func (t *UtilizationTarget) Reconfigure(latest Target) bool {
+	l, ok := latest.(*UtilizationTarget)
+	if !ok {
+		return false
+	}
+
+	t.setPoint = l.setPoint
+	return true
+}
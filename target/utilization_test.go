@@ -0,0 +1,58 @@
+package target
+
+import (
+	"testing"
+)
+
+func TestUtilization(t *testing.T) {
+	u := NewUtilizationTarget(100)
+
+	tests := []struct {
+		running   int
+		current   int
+		meeting   bool
+		exceeding bool
+		delta     int
+	}{
+		{running: 0, current: 0, meeting: true, exceeding: false, delta: 0},
+		{running: 0, current: 250, meeting: false, exceeding: false, delta: 3},
+		{running: 2, current: 400, meeting: false, exceeding: false, delta: 2},
+		{running: 4, current: 420, meeting: true, exceeding: false, delta: 0}, // within tolerance
+		{running: 4, current: 380, meeting: true, exceeding: false, delta: 0},
+		{running: 4, current: 200, meeting: true, exceeding: true, delta: -2},
+		{running: 4, current: 0, meeting: true, exceeding: true, delta: -4},
+	}
+
+	for i, tc := range tests {
+		u.Observe(tc.running)
+
+		if u.Meeting(tc.current) != tc.meeting {
+			t.Errorf("%d: expected meeting %t", i, tc.meeting)
+		}
+
+		if u.Exceeding(tc.current) != tc.exceeding {
+			t.Errorf("%d: expected exceeding %t", i, tc.exceeding)
+		}
+
+		if d := u.Delta(tc.current); d != tc.delta {
+			t.Errorf("%d: expected delta %d but was %d", i, tc.delta, d)
+		}
+	}
+}
+
+func TestUtilizationReconfigure(t *testing.T) {
+	u := NewUtilizationTarget(100)
+	u.Observe(3)
+
+	if !u.Reconfigure(NewUtilizationTarget(70)) {
+		t.Fatalf("Should be able to reconfigure with another utilization target")
+	}
+
+	if u.SetPoint() != 70 || u.running != 3 {
+		t.Fatalf("Expected set point 70 with 3 running but was %d with %d", u.SetPoint(), u.running)
+	}
+
+	if u.Reconfigure(NewRemainderTarget(10)) {
+		t.Fatalf("Shouldn't be able to reconfigure with a different target type")
+	}
+}
@@ -0,0 +1,36 @@
+package target
+
+// queueVelocity keeps the last few changes in queue length, so that we can average over them rather than
+// reacting to every bit of noise. The number of samples comes from MSS_VEL_SAMPLES or the velSamples config.
+type queueVelocity struct {
+	changes []float64 // one more than we need, with the newest at the end
+	samples int
+	count   int
+}
+
+func newQueueVelocity(samples int) queueVelocity {
+	return queueVelocity{
+		changes: make([]float64, samples+1),
+		samples: samples,
+	}
+}
+
+// add records the latest change in queue length and returns the average over the last few samples, or over
+// as many as we have so far
+func (v *queueVelocity) add(change float64) float64 {
+	var total float64
+
+	v.changes[v.samples] = change
+	for i := 0; i <= v.samples-1; i++ {
+		v.changes[i] = v.changes[i+1]
+		total = total + v.changes[i]
+	}
+
+	if v.count < v.samples {
+		v.count++
+		// Only the newest count samples are real, the rest are still zero
+		return total / float64(v.count)
+	}
+
+	return total / float64(v.samples)
+}
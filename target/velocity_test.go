@@ -0,0 +1,27 @@
+package target
+
+import (
+	"testing"
+)
+
+func TestQueueVelocity(t *testing.T) {
+	v := newQueueVelocity(3)
+
+	// Only average over the samples we have so far
+	if ave := v.add(6); ave != 6 {
+		t.Fatalf("Expected 6 from the first sample but was %f", ave)
+	}
+
+	if ave := v.add(0); ave != 3 {
+		t.Fatalf("Expected 3 from two samples but was %f", ave)
+	}
+
+	if ave := v.add(-3); ave != 1 {
+		t.Fatalf("Expected 1 from three samples but was %f", ave)
+	}
+
+	// The oldest sample drops out
+	if ave := v.add(-9); ave != -4 {
+		t.Fatalf("Expected -4 from the last three samples but was %f", ave)
+	}
+}
@@ -0,0 +1,36 @@
+// Package utils contains common shared code.
+package utils
+
+import (
+	"k8s.io/client-go/1.5/pkg/api/resource"
+)
+
+// ParseCPU converts a Kubernetes-style CPU quantity such as "500m" or "2" into millicores.
+// An empty string is 0, meaning CPU isn't being accounted for.
+func ParseCPU(s string) (millicores int64, err error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.MilliValue(), nil
+}
+
+// ParseMemory converts a Kubernetes-style memory quantity such as "256Mi" or "1G" into bytes.
+// An empty string is 0, meaning memory isn't being accounted for.
+func ParseMemory(s string) (bytes int64, err error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.Value(), nil
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestParseCPU(t *testing.T) {
+	tests := []struct {
+		s       string
+		exp     int64
+		success bool
+	}{
+		{s: "", exp: 0, success: true},
+		{s: "500m", exp: 500, success: true},
+		{s: "2", exp: 2000, success: true},
+		{s: "0.25", exp: 250, success: true},
+		{s: "lots", success: false},
+	}
+
+	for _, test := range tests {
+		v, err := ParseCPU(test.s)
+		if test.success && err != nil {
+			t.Fatalf("Unexpected error parsing %s: %v", test.s, err)
+		}
+		if !test.success && err == nil {
+			t.Fatalf("Expected an error parsing %s", test.s)
+		}
+		if v != test.exp {
+			t.Fatalf("Expected %s to be %d millicores but was %d", test.s, test.exp, v)
+		}
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		s       string
+		exp     int64
+		success bool
+	}{
+		{s: "", exp: 0, success: true},
+		{s: "256Mi", exp: 256 * 1024 * 1024, success: true},
+		{s: "1G", exp: 1000 * 1000 * 1000, success: true},
+		{s: "1024", exp: 1024, success: true},
+		{s: "plenty", success: false},
+	}
+
+	for _, test := range tests {
+		v, err := ParseMemory(test.s)
+		if test.success && err != nil {
+			t.Fatalf("Unexpected error parsing %s: %v", test.s, err)
+		}
+		if !test.success && err == nil {
+			t.Fatalf("Expected an error parsing %s", test.s)
+		}
+		if v != test.exp {
+			t.Fatalf("Expected %s to be %d bytes but was %d", test.s, test.exp, v)
+		}
+	}
+}